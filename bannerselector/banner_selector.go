@@ -7,45 +7,88 @@ import (
 
 var errIncorrectInput = errors.New("incorrect input")
 
-const invalidIndex = -1
+const (
+	invalidIndex       = -1
+	defaultExploration = 1.0
+)
 
-func ucb1(sump, p int, q float64) float64 {
-	return q + math.Sqrt(2*math.Log(float64(sump))/float64(p))
+func ucb1(sump, p int, q, c float64) float64 {
+	return q + c*math.Sqrt(2*math.Log(float64(sump))/float64(p))
 }
 
 func isCorrectInput(click, display int) bool {
 	return click >= 0 && display >= 0 && display >= click
 }
 
-func SelectBannerIndex(displays []int, clicks []int) (int, error) {
-	if len(displays) != len(clicks) || len(displays) == 0 {
-		return invalidIndex, errIncorrectInput
+type ucb1Strategy struct {
+	exploration float64
+}
+
+func newUCB1(params Params) (Strategy, error) {
+	exploration := paramOr(params.Exploration, defaultExploration)
+	if exploration < 0 {
+		return nil, ErrIncorrectParams
+	}
+	return &ucb1Strategy{exploration: exploration}, nil
+}
+
+func (s *ucb1Strategy) Select(stats []ArmStats) (int, error) {
+	if err := validateStats(stats); err != nil {
+		return invalidIndex, err
+	}
+	if index, ok := guaranteedShare(stats); ok {
+		return index, nil
+	}
+	// Сначала показываем баннеры, которые еще не показывались
+	if index, ok := firstNotDisplayed(stats); ok {
+		return index, nil
 	}
 
-	N := len(displays) // количество баннеров в выборке
 	var sumDisplays int
-	for i := 0; i < N; i++ {
-		sumDisplays += displays[i]
+	for _, arm := range stats {
+		sumDisplays += arm.Displays
 	}
 
 	// Определяем баннер для показа
 	var maxUcb float64
-	var bIndex int
-	for i := 0; i < N; i++ {
-		if !isCorrectInput(clicks[i], displays[i]) {
-			return invalidIndex, errIncorrectInput
+	bIndex := firstCandidate(stats)
+	for i, arm := range stats {
+		if arm.Excluded {
+			continue
 		}
-		if displays[i] == 0 {
-			return i, nil
-		}
-
-		q := float64(clicks[i]) / float64(displays[i])
-		ucb := ucb1(sumDisplays, displays[i], q)
+		ucb := ucb1(sumDisplays, arm.Displays, arm.CTR(), s.exploration) * arm.weight()
 		if maxUcb < ucb {
 			maxUcb = ucb
 			bIndex = i
 		}
 	}
-
 	return bIndex, nil
 }
+
+// UCB1Score возвращает текущую оценку UCB1 баннера с индексом index.
+// Для баннера без показов оценка не определена и ok равно false.
+// Незаданный коэффициент исследования принимает значение по умолчанию.
+func UCB1Score(stats []ArmStats, index int, exploration *float64) (score float64, ok bool) {
+	if index < 0 || index >= len(stats) || stats[index].Displays == 0 {
+		return 0, false
+	}
+	var sumDisplays int
+	for _, arm := range stats {
+		sumDisplays += arm.Displays
+	}
+	arm := stats[index]
+	return ucb1(sumDisplays, arm.Displays, arm.CTR(), paramOr(exploration, defaultExploration)), true
+}
+
+func SelectBannerIndex(displays []int, clicks []int) (int, error) {
+	if len(displays) != len(clicks) {
+		return invalidIndex, errIncorrectInput
+	}
+
+	stats := make([]ArmStats, len(displays))
+	for i := range displays {
+		stats[i] = ArmStats{Displays: displays[i], Clicks: clicks[i]}
+	}
+	strategy := ucb1Strategy{exploration: defaultExploration}
+	return strategy.Select(stats)
+}
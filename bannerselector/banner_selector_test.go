@@ -1,6 +1,7 @@
 package bannerselector
 
 import (
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -160,3 +161,100 @@ func TestNegativeInput(t *testing.T) {
 		require.Equal(t, index, invalidIndex)
 	})
 }
+
+func TestUCB1Score(t *testing.T) {
+	stats := []ArmStats{{Displays: 10, Clicks: 5}, {Displays: 10, Clicks: 1}, {}}
+
+	score, ok := UCB1Score(stats, 0, nil)
+	require.True(t, ok)
+	require.InDelta(t, 0.5+math.Sqrt(2*math.Log(20)/10), score, 1e-9)
+
+	better, _ := UCB1Score(stats, 0, nil)
+	worse, _ := UCB1Score(stats, 1, nil)
+	require.Greater(t, better, worse)
+
+	_, ok = UCB1Score(stats, 2, nil)
+	require.False(t, ok)
+	_, ok = UCB1Score(stats, 5, nil)
+	require.False(t, ok)
+}
+
+func TestThompsonSampling(t *testing.T) {
+	t.Run("obvious winner", func(t *testing.T) {
+		strategy, _ := NewStrategy(ThompsonSampling, Params{})
+		stats := []ArmStats{
+			{Displays: 10000, Clicks: 100},
+			{Displays: 10000, Clicks: 9000},
+			{Displays: 10000, Clicks: 500},
+		}
+		for i := 0; i < 100; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err)
+			require.Equal(t, index, 1)
+		}
+	})
+	t.Run("beta sample range", func(t *testing.T) {
+		random := NewRandom(1)
+		for i := 0; i < 1000; i++ {
+			sample := betaSample(random, 0.5, 3)
+			require.GreaterOrEqual(t, sample, 0.0)
+			require.LessOrEqual(t, sample, 1.0)
+		}
+	})
+}
+
+func TestThompsonSamplingSeeded(t *testing.T) {
+	stats := []ArmStats{
+		{Displays: 100, Clicks: 10},
+		{Displays: 100, Clicks: 10},
+		{Displays: 100, Clicks: 10},
+		{Displays: 0, Clicks: 0},
+	}
+	selectAll := func(seed int64) []int {
+		strategy, err := NewStrategy(ThompsonSampling, Params{Random: NewRandom(seed)})
+		require.NoError(t, err)
+		indexes := make([]int, 0, 10)
+		for i := 0; i < 10; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err)
+			indexes = append(indexes, index)
+		}
+		return indexes
+	}
+
+	t.Run("exact choices", func(t *testing.T) {
+		require.Equal(t, selectAll(42), []int{3, 3, 3, 3, 3, 2, 3, 3, 3, 3})
+	})
+	t.Run("same seed same choices", func(t *testing.T) {
+		require.Equal(t, selectAll(7), selectAll(7))
+	})
+	t.Run("equal ranks are spread", func(t *testing.T) {
+		strategy, _ := NewStrategy(ThompsonSampling, Params{Random: NewRandom(1)})
+		equal := []ArmStats{{Displays: 10, Clicks: 3}, {Displays: 10, Clicks: 3}, {Displays: 10, Clicks: 3}}
+		selected := make([]int, len(equal))
+		for i := 0; i < 300; i++ {
+			index, err := strategy.Select(equal)
+			require.NoError(t, err)
+			selected[index]++
+		}
+		require.Greater(t, selected[0], 0)
+		require.Greater(t, selected[1], 0)
+		require.Greater(t, selected[2], 0)
+	})
+	t.Run("priors", func(t *testing.T) {
+		// Сильное априорное распределение выравнивает баннеры с малым числом показов
+		strategy, _ := NewStrategy(ThompsonSampling, Params{Alpha: ptr(1000.0), Beta: ptr(1000.0), Random: NewRandom(3)})
+		selected := make([]int, 2)
+		for i := 0; i < 300; i++ {
+			index, err := strategy.Select([]ArmStats{{Displays: 2, Clicks: 2}, {Displays: 2, Clicks: 0}})
+			require.NoError(t, err)
+			selected[index]++
+		}
+		require.Greater(t, selected[1], 100)
+	})
+	t.Run("incorrect priors", func(t *testing.T) {
+		strategy, err := NewStrategy(ThompsonSampling, Params{Alpha: ptr(-1.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		require.Nil(t, strategy)
+	})
+}
@@ -0,0 +1,48 @@
+package bannerselector
+
+const defaultEpsilon = 0.1
+
+type epsilonGreedyStrategy struct {
+	epsilon float64
+	random  Random
+}
+
+func newEpsilonGreedy(params Params) (Strategy, error) {
+	epsilon := paramOr(params.Epsilon, defaultEpsilon)
+	if epsilon < 0 || epsilon > 1 {
+		return nil, ErrIncorrectParams
+	}
+	return &epsilonGreedyStrategy{epsilon: epsilon, random: params.random()}, nil
+}
+
+func (s *epsilonGreedyStrategy) Select(stats []ArmStats) (int, error) {
+	if err := validateStats(stats); err != nil {
+		return invalidIndex, err
+	}
+	if index, ok := guaranteedShare(stats); ok {
+		return index, nil
+	}
+	if index, ok := firstNotDisplayed(stats); ok {
+		return index, nil
+	}
+
+	// С вероятностью epsilon показываем случайный баннер
+	if s.random.Float64() < s.epsilon {
+		candidates := make([]int, 0, len(stats))
+		for i, arm := range stats {
+			if !arm.Excluded {
+				candidates = append(candidates, i)
+			}
+		}
+		return candidates[s.random.Intn(len(candidates))], nil
+	}
+
+	// Иначе баннер с наибольшим взвешенным CTR
+	bIndex := firstCandidate(stats)
+	for i, arm := range stats {
+		if !arm.Excluded && arm.CTR()*arm.weight() > stats[bIndex].CTR()*stats[bIndex].weight() {
+			bIndex = i
+		}
+	}
+	return bIndex, nil
+}
@@ -0,0 +1,43 @@
+package bannerselector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEpsilonGreedy(t *testing.T) {
+	t.Run("select non displayed", func(t *testing.T) {
+		strategy, _ := NewStrategy(EpsilonGreedy, Params{Epsilon: ptr(1.0)})
+		index, err := strategy.Select([]ArmStats{
+			{Displays: 10, Clicks: 5},
+			{Displays: 0, Clicks: 0},
+			{Displays: 10, Clicks: 1},
+		})
+		require.NoError(t, err)
+		require.Equal(t, index, 1)
+	})
+	t.Run("exploit the best", func(t *testing.T) {
+		strategy := epsilonGreedyStrategy{epsilon: 0, random: NewRandom(1)}
+		index, err := strategy.Select([]ArmStats{
+			{Displays: 10, Clicks: 1},
+			{Displays: 10, Clicks: 5},
+			{Displays: 10, Clicks: 3},
+		})
+		require.NoError(t, err)
+		require.Equal(t, index, 1)
+	})
+	t.Run("explore all", func(t *testing.T) {
+		strategy, _ := NewStrategy(EpsilonGreedy, Params{Epsilon: ptr(1.0)})
+		stats := []ArmStats{{Displays: 10, Clicks: 1}, {Displays: 10, Clicks: 5}, {Displays: 10, Clicks: 3}}
+		selected := make([]int, len(stats))
+		for i := 0; i < 300; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err)
+			selected[index]++
+		}
+		require.Greater(t, selected[0], 0)
+		require.Greater(t, selected[1], 0)
+		require.Greater(t, selected[2], 0)
+	})
+}
@@ -0,0 +1,49 @@
+package bannerselector
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Random - источник случайных чисел для вероятностных алгоритмов.
+// Реализация должна быть безопасной для конкурентного использования.
+type Random interface {
+	Float64() float64
+	NormFloat64() float64
+	Intn(n int) int
+}
+
+// globalRandom использует общий источник пакета math/rand.
+type globalRandom struct{}
+
+func (globalRandom) Float64() float64     { return rand.Float64() }     //nolint:gosec
+func (globalRandom) NormFloat64() float64 { return rand.NormFloat64() } //nolint:gosec
+func (globalRandom) Intn(n int) int       { return rand.Intn(n) }       //nolint:gosec
+
+type lockedRandom struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewRandom создает детерминированный источник случайных чисел с заданным зерном.
+func NewRandom(seed int64) Random {
+	return &lockedRandom{rnd: rand.New(rand.NewSource(seed))} //nolint:gosec
+}
+
+func (r *lockedRandom) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Float64()
+}
+
+func (r *lockedRandom) NormFloat64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.NormFloat64()
+}
+
+func (r *lockedRandom) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Intn(n)
+}
@@ -0,0 +1,59 @@
+package bannerselector
+
+import "math"
+
+const defaultTemperature = 0.1
+
+type softmaxStrategy struct {
+	temperature float64
+	random      Random
+}
+
+func newSoftmax(params Params) (Strategy, error) {
+	temperature := paramOr(params.Temperature, defaultTemperature)
+	if temperature <= 0 {
+		return nil, ErrIncorrectParams
+	}
+	return &softmaxStrategy{temperature: temperature, random: params.random()}, nil
+}
+
+func (s *softmaxStrategy) Select(stats []ArmStats) (int, error) {
+	if err := validateStats(stats); err != nil {
+		return invalidIndex, err
+	}
+	if index, ok := guaranteedShare(stats); ok {
+		return index, nil
+	}
+	if index, ok := firstNotDisplayed(stats); ok {
+		return index, nil
+	}
+
+	// Вероятность показа пропорциональна exp(CTR * вес / T).
+	// Вычитаем максимум, чтобы избежать переполнения
+	// Исключенные баннеры получают нулевой вес
+	last := firstCandidate(stats)
+	maxScore := stats[last].CTR() * stats[last].weight()
+	for i, arm := range stats {
+		if !arm.Excluded {
+			maxScore = math.Max(maxScore, arm.CTR()*arm.weight())
+			last = i
+		}
+	}
+	weights := make([]float64, len(stats))
+	var sum float64
+	for i, arm := range stats {
+		if !arm.Excluded {
+			weights[i] = math.Exp((arm.CTR()*arm.weight() - maxScore) / s.temperature)
+			sum += weights[i]
+		}
+	}
+
+	point := s.random.Float64() * sum
+	for i, weight := range weights {
+		point -= weight
+		if point < 0 && weight > 0 {
+			return i, nil
+		}
+	}
+	return last, nil
+}
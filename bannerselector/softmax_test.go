@@ -0,0 +1,44 @@
+package bannerselector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftmax(t *testing.T) {
+	t.Run("select non displayed", func(t *testing.T) {
+		strategy, _ := NewStrategy(Softmax, Params{})
+		index, err := strategy.Select([]ArmStats{
+			{Displays: 10, Clicks: 5},
+			{Displays: 10, Clicks: 1},
+			{Displays: 0, Clicks: 0},
+		})
+		require.NoError(t, err)
+		require.Equal(t, index, 2)
+	})
+	t.Run("low temperature is greedy", func(t *testing.T) {
+		strategy, _ := NewStrategy(Softmax, Params{Temperature: ptr(0.001)})
+		for i := 0; i < 100; i++ {
+			index, err := strategy.Select([]ArmStats{
+				{Displays: 10, Clicks: 1},
+				{Displays: 10, Clicks: 5},
+				{Displays: 10, Clicks: 3},
+			})
+			require.NoError(t, err)
+			require.Equal(t, index, 1)
+		}
+	})
+	t.Run("high temperature explores", func(t *testing.T) {
+		strategy, _ := NewStrategy(Softmax, Params{Temperature: ptr(100.0)})
+		stats := []ArmStats{{Displays: 10, Clicks: 1}, {Displays: 10, Clicks: 5}, {Displays: 10, Clicks: 3}}
+		selected := make([]int, len(stats))
+		for i := 0; i < 300; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err)
+			selected[index]++
+		}
+		require.Greater(t, selected[0], 0)
+		require.Greater(t, selected[2], 0)
+	})
+}
@@ -0,0 +1,219 @@
+package bannerselector
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("unknown selection algorithm")
+	ErrIncorrectParams  = errors.New("incorrect algorithm params")
+)
+
+// Названия алгоритмов, зарегистрированных в пакете.
+const (
+	UCB1             = "ucb1"
+	EpsilonGreedy    = "epsilon_greedy"
+	ThompsonSampling = "thompson_sampling"
+	Softmax          = "softmax"
+	DiscountedUCB    = "discounted_ucb"
+	SlidingWindowUCB = "sliding_window_ucb"
+
+	DefaultAlgorithm = UCB1
+)
+
+// ShareTolerance - допустимое превышение единицы суммой гарантированных долей из-за округления.
+const ShareTolerance = 1e-9
+
+// ArmStats - статистика одного баннера (ручки многорукого бандита).
+// History заполняется только для алгоритмов HistoryStrategy.
+// Weight умножает оценку баннера алгоритмом, нулевой вес равен 1.
+// Share - гарантированная доля баннера среди всех показов, сумма долей не больше 1.
+// Excluded - баннер не выбирается, но его показы учитываются в общем числе показов.
+type ArmStats struct {
+	Displays int
+	Clicks   int
+	History  []BucketStats
+	Weight   float64
+	Share    float64
+	Excluded bool
+}
+
+// BucketStats - показы и переходы баннера за час, начавшийся Age часов назад (0 - текущий час).
+type BucketStats struct {
+	Age      int
+	Displays int
+	Clicks   int
+}
+
+// CTR возвращает долю переходов от показов.
+func (a ArmStats) CTR() float64 {
+	if a.Displays == 0 {
+		return 0
+	}
+	return float64(a.Clicks) / float64(a.Displays)
+}
+
+func (a ArmStats) weight() float64 {
+	if a.Weight == 0 {
+		return 1
+	}
+	return a.Weight
+}
+
+// Strategy выбирает индекс баннера для показа по статистике баннеров.
+type Strategy interface {
+	Select(stats []ArmStats) (int, error)
+}
+
+// HistoryStrategy - алгоритм, учитывающий давность показов и переходов.
+// HistoryHours возвращает, за сколько последних часов нужна почасовая статистика.
+type HistoryStrategy interface {
+	Strategy
+	HistoryHours() int
+}
+
+// Params - параметры алгоритмов. Незаданный (nil) параметр принимает значение по умолчанию,
+// заданный проверяется как есть: например, Epsilon = 0 означает выбор без случайных показов.
+type Params struct {
+	Epsilon     *float64 // доля случайных показов epsilon-greedy, от 0 до 1
+	Exploration *float64 // коэффициент исследования UCB1, не меньше 0
+	Temperature *float64 // температура softmax, больше 0
+	Alpha       *float64 // априорное число переходов Thompson sampling, больше 0
+	Beta        *float64 // априорное число показов без перехода Thompson sampling, больше 0
+	Discount    *float64 // коэффициент затухания за час discounted UCB, больше 0 и меньше 1
+	Window      *int     // размер окна в часах sliding-window UCB, больше 0
+
+	// Random - источник случайных чисел вероятностных алгоритмов, nil - общий источник math/rand
+	Random Random
+}
+
+// random возвращает источник случайных чисел из параметров или общий источник пакета math/rand.
+func (p Params) random() Random {
+	if p.Random == nil {
+		return globalRandom{}
+	}
+	return p.Random
+}
+
+// paramOr возвращает значение параметра или значение по умолчанию, если параметр не задан.
+func paramOr[T any](param *T, defaultValue T) T {
+	if param == nil {
+		return defaultValue
+	}
+	return *param
+}
+
+type Factory func(params Params) (Strategy, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register добавляет алгоритм выбора баннера под заданным именем.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("bannerselector: Register factory is nil")
+	}
+	if _, exists := registry[name]; exists {
+		panic("bannerselector: Register called twice for algorithm " + name)
+	}
+	registry[name] = factory
+}
+
+// NewStrategy создает алгоритм по имени. Пустое имя соответствует DefaultAlgorithm.
+func NewStrategy(name string, params Params) (Strategy, error) {
+	if name == "" {
+		name = DefaultAlgorithm
+	}
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, name)
+	}
+	return factory(params)
+}
+
+// Algorithms возвращает отсортированный список зарегистрированных алгоритмов.
+func Algorithms() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validateStats(stats []ArmStats) error {
+	if len(stats) == 0 {
+		return errIncorrectInput
+	}
+	var shares float64
+	for _, arm := range stats {
+		if !isCorrectInput(arm.Clicks, arm.Displays) || arm.Weight < 0 || arm.Share < 0 {
+			return errIncorrectInput
+		}
+		shares += arm.Share
+	}
+	if shares > 1+ShareTolerance || firstCandidate(stats) == invalidIndex {
+		return errIncorrectInput
+	}
+	return nil
+}
+
+// firstCandidate возвращает индекс первого баннера, доступного для выбора.
+func firstCandidate(stats []ArmStats) int {
+	for i, arm := range stats {
+		if !arm.Excluded {
+			return i
+		}
+	}
+	return invalidIndex
+}
+
+// guaranteedShare возвращает индекс баннера, доля показов которого с учетом
+// следующего показа окажется меньше гарантированной. Из нескольких таких баннеров
+// выбирается баннер с наибольшим отставанием, остальные показы распределяет алгоритм.
+func guaranteedShare(stats []ArmStats) (int, bool) {
+	var sumDisplays int
+	for _, arm := range stats {
+		sumDisplays += arm.Displays
+	}
+	bIndex := invalidIndex
+	maxDeficit := 0.0
+	for i, arm := range stats {
+		deficit := arm.Share*float64(sumDisplays+1) - float64(arm.Displays)
+		if !arm.Excluded && deficit > maxDeficit {
+			maxDeficit = deficit
+			bIndex = i
+		}
+	}
+	return bIndex, bIndex != invalidIndex
+}
+
+// firstNotDisplayed возвращает индекс первого ни разу не показанного баннера.
+func firstNotDisplayed(stats []ArmStats) (int, bool) {
+	for i, arm := range stats {
+		if !arm.Excluded && arm.Displays == 0 {
+			return i, true
+		}
+	}
+	return invalidIndex, false
+}
+
+func init() {
+	Register(UCB1, newUCB1)
+	Register(EpsilonGreedy, newEpsilonGreedy)
+	Register(ThompsonSampling, newThompsonSampling)
+	Register(Softmax, newSoftmax)
+	Register(DiscountedUCB, newDiscountedUCB)
+	Register(SlidingWindowUCB, newSlidingWindowUCB)
+}
@@ -0,0 +1,172 @@
+package bannerselector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStrategy(t *testing.T) {
+	t.Run("registered algorithms", func(t *testing.T) {
+		for _, name := range Algorithms() {
+			strategy, err := NewStrategy(name, Params{})
+			require.NoError(t, err, name)
+			require.NotNil(t, strategy, name)
+		}
+		require.ElementsMatch(t, Algorithms(), []string{
+			UCB1, EpsilonGreedy, ThompsonSampling, Softmax, DiscountedUCB, SlidingWindowUCB,
+		})
+	})
+	t.Run("default algorithm", func(t *testing.T) {
+		strategy, err := NewStrategy("", Params{})
+		require.NoError(t, err)
+		require.IsType(t, &ucb1Strategy{}, strategy)
+	})
+	t.Run("unknown algorithm", func(t *testing.T) {
+		strategy, err := NewStrategy("unknown", Params{})
+		require.ErrorIs(t, err, ErrUnknownAlgorithm)
+		require.Nil(t, strategy)
+	})
+	t.Run("incorrect params", func(t *testing.T) {
+		_, err := NewStrategy(UCB1, Params{Exploration: ptr(-1.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewStrategy(EpsilonGreedy, Params{Epsilon: ptr(2.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewStrategy(Softmax, Params{Temperature: ptr(-0.1)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewStrategy(ThompsonSampling, Params{Alpha: ptr(-1.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		// заданный нулевой параметр не заменяется значением по умолчанию
+		_, err = NewStrategy(Softmax, Params{Temperature: ptr(0.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewStrategy(ThompsonSampling, Params{Beta: ptr(0.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewStrategy(DiscountedUCB, Params{Discount: ptr(0.0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewStrategy(SlidingWindowUCB, Params{Window: ptr(0)})
+		require.ErrorIs(t, err, ErrIncorrectParams)
+	})
+	t.Run("explicit zero params", func(t *testing.T) {
+		// без исследования выбирается баннер с наибольшим CTR
+		stats := []ArmStats{{Displays: 100, Clicks: 10}, {Displays: 1, Clicks: 0}}
+		for _, name := range []string{UCB1, EpsilonGreedy} {
+			strategy, err := NewStrategy(name, Params{Epsilon: ptr(0.0), Exploration: ptr(0.0)})
+			require.NoError(t, err, name)
+			for i := 0; i < 10; i++ {
+				index, err := strategy.Select(stats)
+				require.NoError(t, err, name)
+				require.Equal(t, 0, index, name)
+			}
+		}
+	})
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestRegister(t *testing.T) {
+	require.Panics(t, func() {
+		Register(UCB1, newUCB1)
+	})
+	require.Panics(t, func() {
+		Register("nil factory", nil)
+	})
+}
+
+func TestStrategiesIncorrectInput(t *testing.T) {
+	for _, name := range Algorithms() {
+		strategy, _ := NewStrategy(name, Params{})
+		index, err := strategy.Select(nil)
+		require.ErrorIs(t, err, errIncorrectInput, name)
+		require.Equal(t, index, invalidIndex, name)
+
+		index, err = strategy.Select([]ArmStats{{Displays: 3, Clicks: 4}})
+		require.ErrorIs(t, err, errIncorrectInput, name)
+		require.Equal(t, index, invalidIndex, name)
+
+		index, err = strategy.Select([]ArmStats{{Displays: -1, Clicks: 0}})
+		require.ErrorIs(t, err, errIncorrectInput, name)
+		require.Equal(t, index, invalidIndex, name)
+
+		index, err = strategy.Select([]ArmStats{{Weight: -1}})
+		require.ErrorIs(t, err, errIncorrectInput, name)
+		require.Equal(t, index, invalidIndex, name)
+
+		index, err = strategy.Select([]ArmStats{{Share: 0.6}, {Share: 0.5}})
+		require.ErrorIs(t, err, errIncorrectInput, name)
+		require.Equal(t, index, invalidIndex, name)
+	}
+}
+
+func TestStrategiesGuaranteedShare(t *testing.T) {
+	for _, name := range Algorithms() {
+		strategy, _ := NewStrategy(name, Params{})
+		// у баннера с гарантированной долей CTR хуже остальных
+		stats := []ArmStats{{Share: 0.3}, {}, {}}
+		for i := 0; i < 1000; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err, name)
+			if index != 0 && i%5 == 0 {
+				stats[index].Clicks++
+			}
+			stats[index].Displays++
+			require.GreaterOrEqual(t, float64(stats[0].Displays), 0.3*float64(i+1)-1, name)
+		}
+	}
+}
+
+func TestStrategiesWeight(t *testing.T) {
+	for _, name := range Algorithms() {
+		strategy, _ := NewStrategy(name, Params{})
+		stats := []ArmStats{{Displays: 50, Clicks: 3}, {Displays: 50, Clicks: 3, Weight: 3}}
+		for i := 0; i < 2000; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err, name)
+			stats[index].Displays++
+			if i%17 == 0 {
+				stats[index].Clicks++
+			}
+		}
+		require.Greater(t, stats[1].Displays, stats[0].Displays, name)
+	}
+}
+
+func TestStrategiesExcluded(t *testing.T) {
+	for _, name := range Algorithms() {
+		strategy, _ := NewStrategy(name, Params{})
+		// исключенные баннеры лучше остальных, не показаны или отстают от гарантированной доли
+		stats := []ArmStats{
+			{Displays: 50, Clicks: 40, Excluded: true},
+			{Displays: 50, Clicks: 3},
+			{Share: 0.5, Excluded: true},
+			{Displays: 50, Clicks: 5},
+		}
+		for i := 0; i < 500; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err, name)
+			require.Contains(t, []int{1, 3}, index, name)
+		}
+
+		index, err := strategy.Select([]ArmStats{{Excluded: true}, {Displays: 5, Excluded: true}})
+		require.ErrorIs(t, err, errIncorrectInput, name)
+		require.Equal(t, index, invalidIndex, name)
+	}
+}
+
+func TestStrategiesTheMostPopular(t *testing.T) {
+	for _, name := range Algorithms() {
+		strategy, _ := NewStrategy(name, Params{})
+		stats := []ArmStats{{Displays: 50, Clicks: 3}, {Displays: 50, Clicks: 3}, {Displays: 50, Clicks: 3}}
+		for i := 0; i < 2000; i++ {
+			index, err := strategy.Select(stats)
+			require.NoError(t, err, name)
+			if index == 0 {
+				stats[index].Clicks++
+			}
+			stats[index].Displays++
+		}
+		require.Greater(t, stats[0].Displays, stats[1].Displays, name)
+		require.Greater(t, stats[0].Displays, stats[2].Displays, name)
+	}
+}
@@ -0,0 +1,75 @@
+package bannerselector
+
+import (
+	"math"
+)
+
+const defaultPrior = 1.0
+
+type thompsonSamplingStrategy struct {
+	alpha  float64
+	beta   float64
+	random Random
+}
+
+func newThompsonSampling(params Params) (Strategy, error) {
+	alpha, beta := paramOr(params.Alpha, defaultPrior), paramOr(params.Beta, defaultPrior)
+	if alpha <= 0 || beta <= 0 {
+		return nil, ErrIncorrectParams
+	}
+	return &thompsonSamplingStrategy{alpha: alpha, beta: beta, random: params.random()}, nil
+}
+
+func (s *thompsonSamplingStrategy) Select(stats []ArmStats) (int, error) {
+	if err := validateStats(stats); err != nil {
+		return invalidIndex, err
+	}
+	if index, ok := guaranteedShare(stats); ok {
+		return index, nil
+	}
+
+	// Для каждого баннера берем выборку из апостериорного распределения CTR
+	// Beta(clicks + alpha, displays - clicks + beta) и показываем баннер с максимальной
+	bIndex := 0
+	maxSample := -1.0
+	for i, arm := range stats {
+		if arm.Excluded {
+			continue
+		}
+		sample := betaSample(s.random, float64(arm.Clicks)+s.alpha, float64(arm.Displays-arm.Clicks)+s.beta) *
+			arm.weight()
+		if sample > maxSample {
+			maxSample = sample
+			bIndex = i
+		}
+	}
+	return bIndex, nil
+}
+
+func betaSample(random Random, alpha, beta float64) float64 {
+	x := gammaSample(random, alpha)
+	y := gammaSample(random, beta)
+	return x / (x + y)
+}
+
+// gammaSample - выборка из Gamma(shape, 1) методом Марсальи-Цанга.
+func gammaSample(random Random, shape float64) float64 {
+	if shape < 1 {
+		u := random.Float64()
+		return gammaSample(random, shape+1) * math.Pow(u, 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := random.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := random.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package bannerselector
+
+import "math"
+
+const (
+	defaultDiscount = 0.95
+	defaultWindow   = 24
+	// minDiscountWeight - вес, начиная с которого старые часы не учитываются discounted UCB
+	minDiscountWeight = 1e-3
+)
+
+// windowedUCB - UCB1 по взвешенным почасовым счетчикам: вклад часа зависит от его давности.
+type windowedUCB struct {
+	exploration float64
+	hours       int
+	weight      func(age int) float64
+}
+
+func newDiscountedUCB(params Params) (Strategy, error) {
+	exploration := paramOr(params.Exploration, defaultExploration)
+	discount := paramOr(params.Discount, defaultDiscount)
+	if exploration < 0 || discount <= 0 || discount >= 1 {
+		return nil, ErrIncorrectParams
+	}
+	return &windowedUCB{
+		exploration: exploration,
+		hours:       int(math.Ceil(math.Log(minDiscountWeight) / math.Log(discount))),
+		weight: func(age int) float64 {
+			return math.Pow(discount, float64(age))
+		},
+	}, nil
+}
+
+func newSlidingWindowUCB(params Params) (Strategy, error) {
+	exploration := paramOr(params.Exploration, defaultExploration)
+	window := paramOr(params.Window, defaultWindow)
+	if exploration < 0 || window <= 0 {
+		return nil, ErrIncorrectParams
+	}
+	return &windowedUCB{
+		exploration: exploration,
+		hours:       window,
+		weight: func(age int) float64 {
+			if age < window {
+				return 1
+			}
+			return 0
+		},
+	}, nil
+}
+
+func (s *windowedUCB) HistoryHours() int {
+	return s.hours
+}
+
+// weighted возвращает взвешенные показы и переходы баннера.
+// Без истории все показы считаются сделанными в текущем часе.
+func (s *windowedUCB) weighted(arm ArmStats) (float64, float64) {
+	if arm.History == nil {
+		return float64(arm.Displays), float64(arm.Clicks)
+	}
+	var displays, clicks float64
+	for _, bucket := range arm.History {
+		if bucket.Age < 0 || bucket.Age >= s.hours {
+			continue
+		}
+		weight := s.weight(bucket.Age)
+		displays += weight * float64(bucket.Displays)
+		clicks += weight * float64(bucket.Clicks)
+	}
+	return displays, clicks
+}
+
+func (s *windowedUCB) Select(stats []ArmStats) (int, error) {
+	if err := validateStats(stats); err != nil {
+		return invalidIndex, err
+	}
+	if index, ok := guaranteedShare(stats); ok {
+		return index, nil
+	}
+
+	displays := make([]float64, len(stats))
+	clicks := make([]float64, len(stats))
+	var sumDisplays float64
+	for i, arm := range stats {
+		displays[i], clicks[i] = s.weighted(arm)
+		sumDisplays += displays[i]
+	}
+
+	// Баннеры без недавних показов показываются в первую очередь
+	bIndex := invalidIndex
+	maxUcb := math.Inf(-1)
+	for i := range stats {
+		if stats[i].Excluded {
+			continue
+		}
+		if displays[i] == 0 {
+			return i, nil
+		}
+		// переход может быть учтен в следующем часе после показа
+		ctr := math.Min(clicks[i]/displays[i], 1)
+		ucb := (ctr + s.exploration*math.Sqrt(2*math.Log(math.Max(sumDisplays, 1))/displays[i])) * stats[i].weight()
+		if ucb > maxUcb {
+			maxUcb = ucb
+			bIndex = i
+		}
+	}
+	return bIndex, nil
+}
@@ -0,0 +1,73 @@
+package bannerselector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindowUCB(t *testing.T) {
+	strategy, err := NewStrategy(SlidingWindowUCB, Params{Window: ptr(24)})
+	require.NoError(t, err)
+	require.Equal(t, 24, strategy.(HistoryStrategy).HistoryHours())
+
+	t.Run("old popularity is forgotten", func(t *testing.T) {
+		// Первый баннер был популярен неделю назад, но в последние сутки переходов нет
+		stats := []ArmStats{
+			{Displays: 1100, Clicks: 500, History: []BucketStats{
+				{Age: 168, Displays: 1000, Clicks: 500}, {Age: 1, Displays: 100, Clicks: 0},
+			}},
+			{Displays: 100, Clicks: 10, History: []BucketStats{{Age: 2, Displays: 100, Clicks: 10}}},
+		}
+		index, err := strategy.Select(stats)
+		require.NoError(t, err)
+		require.Equal(t, 1, index)
+
+		ucb, err := NewStrategy(UCB1, Params{})
+		require.NoError(t, err)
+		index, err = ucb.Select(stats)
+		require.NoError(t, err)
+		require.Equal(t, 0, index)
+	})
+
+	t.Run("no recent displays", func(t *testing.T) {
+		stats := []ArmStats{
+			{Displays: 10, Clicks: 1, History: []BucketStats{{Age: 0, Displays: 10, Clicks: 1}}},
+			{Displays: 10, Clicks: 5, History: []BucketStats{{Age: 30, Displays: 10, Clicks: 5}}},
+		}
+		index, err := strategy.Select(stats)
+		require.NoError(t, err)
+		require.Equal(t, 1, index)
+	})
+
+	t.Run("without history", func(t *testing.T) {
+		index, err := strategy.Select([]ArmStats{{Displays: 10, Clicks: 1}, {Displays: 10, Clicks: 5}})
+		require.NoError(t, err)
+		require.Equal(t, 1, index)
+	})
+}
+
+func TestDiscountedUCB(t *testing.T) {
+	strategy, err := NewStrategy(DiscountedUCB, Params{Discount: ptr(0.5)})
+	require.NoError(t, err)
+	require.Equal(t, 10, strategy.(HistoryStrategy).HistoryHours())
+
+	stats := []ArmStats{
+		{Displays: 200, Clicks: 100, History: []BucketStats{
+			{Age: 5, Displays: 100, Clicks: 100}, {Age: 0, Displays: 100, Clicks: 0},
+		}},
+		{Displays: 100, Clicks: 30, History: []BucketStats{{Age: 0, Displays: 100, Clicks: 30}}},
+	}
+	index, err := strategy.Select(stats)
+	require.NoError(t, err)
+	require.Equal(t, 1, index)
+}
+
+func TestWindowedUCBIncorrectParams(t *testing.T) {
+	for _, params := range []Params{{Discount: ptr(1.0)}, {Discount: ptr(-0.5)}, {Exploration: ptr(-1.0)}} {
+		_, err := NewStrategy(DiscountedUCB, params)
+		require.ErrorIs(t, err, ErrIncorrectParams)
+	}
+	_, err := NewStrategy(SlidingWindowUCB, Params{Window: ptr(-1)})
+	require.ErrorIs(t, err, ErrIncorrectParams)
+}
@@ -2,6 +2,7 @@ package configs
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -23,3 +24,27 @@ func TestCreateMsgBrokerConfig(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, conn)
 }
+
+func TestCreateStatisticsConfig(t *testing.T) {
+	conn, err := GetStatisticsConfig("../config/test/test_connection_config.yaml")
+	require.Nil(t, err)
+	require.NotNil(t, conn)
+	require.Equal(t, "postgres", conn.Backend())
+	require.Equal(t, 5*time.Second, conn.FlushInterval())
+	require.Equal(t, 6379, conn.Port())
+}
+
+func TestCreateFrequencyCapConfig(t *testing.T) {
+	conn, err := GetFrequencyCapConfig("../config/test/test_connection_config.yaml")
+	require.Nil(t, err)
+	require.NotNil(t, conn)
+	require.Equal(t, 0, conn.Limit())
+	require.Equal(t, 24*time.Hour, conn.Window())
+}
+
+func TestCreateImpressionConfig(t *testing.T) {
+	conn, err := GetImpressionConfig("../config/test/test_connection_config.yaml")
+	require.Nil(t, err)
+	require.NotNil(t, conn)
+	require.Equal(t, time.Hour, conn.TTL())
+}
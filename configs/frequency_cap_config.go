@@ -0,0 +1,51 @@
+package configs
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FrequencyCapConfig - ограничение числа показов баннера пользователю за окно.
+// Нулевой лимит отключает ограничение.
+type FrequencyCapConfig interface {
+	Limit() int
+	Window() time.Duration
+}
+
+type frequencyCapImpl struct {
+	LimitFC  int           `yaml:"limit"`
+	WindowFC time.Duration `yaml:"window"`
+}
+
+func GetFrequencyCapConfig(filename string) (FrequencyCapConfig, error) {
+	configFile, err := os.Open(filename)
+	if err != nil {
+		return nil, errInputIsNil
+	}
+	defer configFile.Close()
+
+	yamlFile := new(bytes.Buffer)
+	_, err = yamlFile.ReadFrom(configFile)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]frequencyCapImpl)
+
+	err = yaml.Unmarshal(yamlFile.Bytes(), &data)
+	if err != nil {
+		return nil, err
+	}
+	config := data["frequency_cap"]
+	return &config, nil
+}
+
+func (c *frequencyCapImpl) Limit() int {
+	return c.LimitFC
+}
+
+func (c *frequencyCapImpl) Window() time.Duration {
+	return c.WindowFC
+}
@@ -0,0 +1,45 @@
+package configs
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ImpressionConfig - срок действия токена показа, по которому засчитывается переход.
+// Ключ подписи токенов задается переменной окружения IMPRESSION_SECRET.
+type ImpressionConfig interface {
+	TTL() time.Duration
+}
+
+type impressionImpl struct {
+	TTLImpression time.Duration `yaml:"ttl"`
+}
+
+func GetImpressionConfig(filename string) (ImpressionConfig, error) {
+	configFile, err := os.Open(filename)
+	if err != nil {
+		return nil, errInputIsNil
+	}
+	defer configFile.Close()
+
+	yamlFile := new(bytes.Buffer)
+	_, err = yamlFile.ReadFrom(configFile)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]impressionImpl)
+
+	err = yaml.Unmarshal(yamlFile.Bytes(), &data)
+	if err != nil {
+		return nil, err
+	}
+	config := data["impression"]
+	return &config, nil
+}
+
+func (c *impressionImpl) TTL() time.Duration {
+	return c.TTLImpression
+}
@@ -0,0 +1,61 @@
+package configs
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type StatisticsConfig interface {
+	Backend() string
+	FlushInterval() time.Duration
+	Host() string
+	Port() int
+}
+
+type statisticsImpl struct {
+	BackendST string        `yaml:"backend"`
+	FlushST   time.Duration `yaml:"flush"`
+	HostST    string        `yaml:"host"`
+	PortST    int           `yaml:"port"`
+}
+
+func GetStatisticsConfig(filename string) (StatisticsConfig, error) {
+	configFile, err := os.Open(filename)
+	if err != nil {
+		return nil, errInputIsNil
+	}
+	defer configFile.Close()
+
+	yamlFile := new(bytes.Buffer)
+	_, err = yamlFile.ReadFrom(configFile)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]statisticsImpl)
+
+	err = yaml.Unmarshal(yamlFile.Bytes(), &data)
+	if err != nil {
+		return nil, err
+	}
+	config := data["statistics"]
+	return &config, nil
+}
+
+func (c *statisticsImpl) Backend() string {
+	return c.BackendST
+}
+
+func (c *statisticsImpl) FlushInterval() time.Duration {
+	return c.FlushST
+}
+
+func (c *statisticsImpl) Host() string {
+	return c.HostST
+}
+
+func (c *statisticsImpl) Port() int {
+	return c.PortST
+}
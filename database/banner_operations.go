@@ -1,12 +1,63 @@
 package database
 
 import (
+	"fmt"
+	"net/url"
+
 	"github.com/SergeyTyurin/banner-rotation/structures"
 )
 
-func (d *databaseImpl) DatabaseGetBanners() ([]structures.Banner, error) {
-	query := `SELECT id, info FROM "Banners"`
-	rows, err := d.db.Query(query)
+// bannerColumns - столбцы баннера в порядке scanBanner, таблица "Banners" имеет псевдоним b.
+const bannerColumns = `b.id, b.info, b.target_url, b.creative_url, b.width, b.height, b.status,
+	b.created_at, b.updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBanner(row rowScanner) (structures.Banner, error) {
+	var banner structures.Banner
+	err := row.Scan(&banner.ID, &banner.Info, &banner.TargetURL, &banner.CreativeURL,
+		&banner.Width, &banner.Height, &banner.Status, &banner.CreatedAt, &banner.UpdatedAt)
+	return banner, err
+}
+
+// validateURL проверяет, что адрес не задан или является абсолютным адресом http(s).
+func validateURL(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := url.ParseRequestURI(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: %s must be an absolute http(s) URL", ErrIncorrectEntity, field)
+	}
+	return nil
+}
+
+// validateBanner проверяет поля баннера. Пустой статус при создании означает активный баннер,
+// при обновлении - сохранение прежнего статуса.
+func validateBanner(banner *structures.Banner) error {
+	switch banner.Status {
+	case "", structures.BannerActive, structures.BannerPaused, structures.BannerArchived:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrIncorrectEntity, banner.Status)
+	}
+	if banner.Width < 0 || banner.Height < 0 {
+		return fmt.Errorf("%w: negative size", ErrIncorrectEntity)
+	}
+	if err := validateURL("target_url", banner.TargetURL); err != nil {
+		return err
+	}
+	return validateURL("creative_url", banner.CreativeURL)
+}
+
+func (d *databaseImpl) DatabaseGetBanners(params structures.ListParams) ([]structures.Banner, error) {
+	if err := validateListParams(&params); err != nil {
+		return nil, err
+	}
+	condition, args := listCondition(params)
+	query := `SELECT ` + bannerColumns + ` FROM "Banners" b ` + condition
+	rows, err := d.db.Query(query, args...)
 	if err != nil || rows.Err() != nil {
 		return nil, err
 	}
@@ -14,33 +65,29 @@ func (d *databaseImpl) DatabaseGetBanners() ([]structures.Banner, error) {
 
 	banners := make([]structures.Banner, 0)
 	for rows.Next() {
-		var id int
-		var info string
-		err := rows.Scan(&id, &info)
+		banner, err := scanBanner(rows)
 		if err != nil {
 			return nil, err
 		}
-		banners = append(banners, structures.Banner{ID: id, Info: info})
+		banners = append(banners, banner)
 	}
 	return banners, nil
 }
 
 func (d *databaseImpl) DatabaseGetBanner(id int) (structures.Banner, error) {
-	query := `SELECT info FROM "Banners" WHERE id = $1`
-	row := d.db.QueryRow(query, id)
-
-	var info string
-	if err := row.Scan(&info); err != nil {
+	query := `SELECT ` + bannerColumns + ` FROM "Banners" b WHERE id = $1`
+	banner, err := scanBanner(d.db.QueryRow(query, id))
+	if err != nil {
 		return structures.Banner{ID: invalidID}, ErrNotExist
 	}
-	return structures.Banner{ID: id, Info: info}, nil
+	return banner, nil
 }
 
 func (d *databaseImpl) DatabaseDeleteBanner(id int) error {
 	if err := checkEntityIsExists(d, "Banners", id); err != nil {
 		return err
 	}
-	rotationQuery := `DELETE FROM "Statistic" WHERE banner_id = $1`
+	rotationQuery := `DELETE FROM "Rotation" WHERE banner_id = $1`
 	query := `DELETE FROM "Banners" WHERE id = $1`
 
 	tx, err := d.db.Begin()
@@ -69,8 +116,15 @@ func (d *databaseImpl) DatabaseDeleteBanner(id int) error {
 }
 
 func (d *databaseImpl) DatabaseCreateBanner(entity structures.Banner) (structures.Banner, error) {
-	query := `INSERT INTO "Banners" (info) VALUES($1)
-	RETURNING id`
+	if err := validateBanner(&entity); err != nil {
+		return structures.Banner{ID: invalidID}, err
+	}
+	if entity.Status == "" {
+		entity.Status = structures.BannerActive
+	}
+	query := `INSERT INTO "Banners" AS b (info, target_url, creative_url, width, height, status)
+	VALUES($1, $2, $3, $4, $5, $6)
+	RETURNING ` + bannerColumns
 	tx, err := d.db.Begin()
 	if err != nil {
 		return structures.Banner{ID: invalidID}, err
@@ -79,25 +133,29 @@ func (d *databaseImpl) DatabaseCreateBanner(entity structures.Banner) (structure
 		_ = tx.Rollback()
 	}()
 
-	row := tx.QueryRow(query, entity.Info)
-	id := invalidID
-	if err := row.Scan(&id); err != nil {
+	created, err := scanBanner(tx.QueryRow(query, entity.Info, entity.TargetURL, entity.CreativeURL,
+		entity.Width, entity.Height, entity.Status))
+	if err != nil {
 		return structures.Banner{ID: invalidID}, err
 	}
 
 	if err := tx.Commit(); err != nil {
 		return structures.Banner{ID: invalidID}, err
 	}
-	return structures.Banner{ID: id, Info: entity.Info}, nil
+	return created, nil
 }
 
 func (d *databaseImpl) DatabaseUpdateBanner(entity structures.Banner) error {
 	if err := checkEntityIsExists(d, "Banners", entity.ID); err != nil {
 		return err
 	}
+	if err := validateBanner(&entity); err != nil {
+		return err
+	}
 	query := `UPDATE "Banners"
-	SET info = $1
-	WHERE id = $2`
+	SET info = $1, target_url = $2, creative_url = $3, width = $4, height = $5,
+	status = COALESCE(NULLIF($6, ''), status), updated_at = now()
+	WHERE id = $7 AND (status <> $8 OR COALESCE(NULLIF($6, ''), status) = $8)`
 
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -107,13 +165,18 @@ func (d *databaseImpl) DatabaseUpdateBanner(entity structures.Banner) error {
 		_ = tx.Rollback()
 	}()
 
-	res, err := tx.Exec(query, entity.Info, entity.ID)
+	res, err := tx.Exec(query, entity.Info, entity.TargetURL, entity.CreativeURL,
+		entity.Width, entity.Height, entity.Status, entity.ID, structures.BannerArchived)
 	if err != nil {
 		return err
 	}
 
+	// Баннер не обновлен, если удален или если меняется статус архивного баннера
 	if affected, _ := res.RowsAffected(); affected < 1 {
-		return ErrNotExist
+		if err := checkEntityIsExists(d, "Banners", entity.ID); err != nil {
+			return err
+		}
+		return ErrBannerArchived
 	}
 
 	return tx.Commit()
@@ -10,7 +10,7 @@ import (
 )
 
 func TestCreateBanner(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -28,8 +28,49 @@ func TestCreateBanner(t *testing.T) {
 	require.NotEqual(t, newBanner.ID, banner.ID)
 }
 
+func TestBannerFields(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Banners" RESTART IDENTITY CASCADE`)
+
+	created, err := d.DatabaseCreateBanner(structures.Banner{
+		Info: "info", TargetURL: "https://example.com/sale", CreativeURL: "https://cdn.example.com/1.png",
+		Width: 300, Height: 250,
+	})
+	require.NoError(t, err)
+	require.Equal(t, structures.BannerActive, created.Status)
+	require.False(t, created.CreatedAt.IsZero())
+
+	created.Status = structures.BannerArchived
+	require.NoError(t, d.DatabaseUpdateBanner(created))
+	fromDB, err := d.DatabaseGetBanner(created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/sale", fromDB.TargetURL)
+	require.Equal(t, 250, fromDB.Height)
+	require.Equal(t, structures.BannerArchived, fromDB.Status)
+	require.True(t, created.CreatedAt.Equal(fromDB.CreatedAt))
+	require.False(t, fromDB.UpdatedAt.Before(fromDB.CreatedAt))
+
+	// Обновление без статуса сохраняет прежний статус
+	require.NoError(t, d.DatabaseUpdateBanner(structures.Banner{ID: created.ID, Info: "updated"}))
+	fromDB, err = d.DatabaseGetBanner(created.ID)
+	require.NoError(t, err)
+	require.Equal(t, structures.BannerArchived, fromDB.Status)
+	// Статус архивного баннера не меняется
+	err = d.DatabaseUpdateBanner(structures.Banner{ID: created.ID, Status: structures.BannerActive})
+	require.ErrorIs(t, err, ErrBannerArchived)
+	require.ErrorIs(t, d.DatabaseUpdateBanner(structures.Banner{ID: 1000}), ErrNotExist)
+
+	_, err = d.DatabaseCreateBanner(structures.Banner{TargetURL: "/relative"})
+	require.ErrorIs(t, err, ErrIncorrectEntity)
+}
+
 func TestGetBanners(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -44,11 +85,29 @@ func TestGetBanners(t *testing.T) {
 			_, _ = d.DatabaseCreateBanner(banner)
 		}
 
-		banners, err := d.DatabaseGetBanners()
+		banners, err := d.DatabaseGetBanners(structures.ListParams{})
 		require.NoError(t, err)
 		require.Equal(t, len(banners), count)
 	})
 
+	t.Run("get banners page", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Banners" RESTART IDENTITY CASCADE`)
+		for _, info := range []string{"Summer sale", "winter sale", "new year", "SALE"} {
+			_, _ = d.DatabaseCreateBanner(structures.Banner{Info: info})
+		}
+
+		banners, err := d.DatabaseGetBanners(structures.ListParams{
+			Limit: 2, Offset: 1, Sort: structures.SortByInfo, Desc: true, Info: "sale",
+		})
+		require.NoError(t, err)
+		require.Len(t, banners, 2)
+		require.Equal(t, "Summer sale", banners[0].Info)
+		require.Equal(t, 4, banners[1].ID)
+
+		_, err = d.DatabaseGetBanners(structures.ListParams{Sort: "info; DROP TABLE"})
+		require.ErrorIs(t, err, ErrIncorrectListParams)
+	})
+
 	t.Run("get banner by id", func(t *testing.T) {
 		_, _ = d.db.Exec(`TRUNCATE TABLE "Banners" RESTART IDENTITY CASCADE`)
 		for i := 0; i < count; i++ {
@@ -64,7 +123,7 @@ func TestGetBanners(t *testing.T) {
 
 	t.Run("get from empty", func(t *testing.T) {
 		_, _ = d.db.Exec(`TRUNCATE TABLE "Banners" RESTART IDENTITY CASCADE`)
-		banners, err := d.DatabaseGetBanners()
+		banners, err := d.DatabaseGetBanners(structures.ListParams{})
 		require.NoError(t, err)
 		require.Empty(t, banners)
 
@@ -76,7 +135,7 @@ func TestGetBanners(t *testing.T) {
 }
 
 func TestUpdateBanner(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -105,7 +164,7 @@ func TestUpdateBanner(t *testing.T) {
 }
 
 func TestDeleteBanner(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -126,7 +185,7 @@ func TestDeleteBanner(t *testing.T) {
 		err := d.DatabaseDeleteBanner(newBanner.ID)
 		require.NoError(t, err)
 
-		banners, _ := d.DatabaseGetBanners()
+		banners, _ := d.DatabaseGetBanners(structures.ListParams{})
 		require.Empty(t, banners)
 	})
 }
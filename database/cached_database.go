@@ -0,0 +1,473 @@
+package database
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+const defaultFlushInterval = 5 * time.Second
+
+type rotationKey struct {
+	slotID  int
+	groupID int
+}
+
+type statisticKey struct {
+	slotID   int
+	groupID  int
+	bannerID int
+}
+
+type counterDelta struct {
+	displays int
+	clicks   int
+}
+
+// cachedRotation - статистика баннеров пары слот/группа, загруженная в память.
+// Давность часов в истории и периоды показов проверяются в момент загрузки,
+// поэтому начало и окончание периода учитываются не позже следующего сброса кэша.
+type cachedRotation struct {
+	mu        sync.Mutex
+	loaded    bool
+	strategy  bannerselector.Strategy
+	algorithm string
+	banners   []int
+	rows      []structures.Banner
+	stats     []bannerselector.ArmStats
+}
+
+func (r *cachedRotation) index(bannerID int) int {
+	for i, id := range r.banners {
+		if id == bannerID {
+			return i
+		}
+	}
+	return invalidID
+}
+
+// cachedDatabase выбирает баннеры по статистике в памяти и периодически
+// сбрасывает накопленные показы и переходы в "Statistic".
+// Сущности и состав ротации по-прежнему хранятся в Postgres.
+type cachedDatabase struct {
+	*databaseImpl
+	flushInterval time.Duration
+
+	// barrier разделяет операции со статистикой в памяти (чтение)
+	// и сброс кэша или изменение состава ротации (запись)
+	barrier   sync.RWMutex
+	mu        sync.Mutex
+	rotations map[rotationKey]*cachedRotation
+
+	pendingMu sync.Mutex
+	pending   map[historyKey]counterDelta
+	// writeMu упорядочивает запись накопленных счетчиков при чтении статистики
+	writeMu sync.Mutex
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewCachedDatabase(flushInterval time.Duration, opts ...Option) Database {
+	if flushInterval <= 0 {
+		flushInterval = defaultFlushInterval
+	}
+	return &cachedDatabase{
+		databaseImpl:  &databaseImpl{db: nil, options: newOptions(opts)},
+		flushInterval: flushInterval,
+		rotations:     make(map[rotationKey]*cachedRotation),
+		pending:       make(map[historyKey]counterDelta),
+	}
+}
+
+func (c *cachedDatabase) DatabaseConnect(config configs.DBConnectionConfig) (func() error, error) {
+	closeConnect, err := c.databaseImpl.DatabaseConnect(config)
+	if err != nil {
+		return nil, err
+	}
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+	go c.flushLoop(c.stop, c.done)
+
+	return func() error {
+		close(c.stop)
+		<-c.done
+		if err := c.flush(); err != nil {
+			_ = closeConnect()
+			return err
+		}
+		return closeConnect()
+	}, nil
+}
+
+func (c *cachedDatabase) flushLoop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := c.flush(); err != nil {
+				log.Println("statistics flush:", err)
+			}
+		}
+	}
+}
+
+// flush записывает накопленные счетчики в базу и сбрасывает кэш,
+// чтобы следующие выборы увидели изменения других экземпляров сервиса.
+func (c *cachedDatabase) flush() error {
+	c.barrier.Lock()
+	defer c.barrier.Unlock()
+	return c.flushLocked()
+}
+
+func (c *cachedDatabase) flushLocked() error {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	if len(c.pending) > 0 {
+		if err := c.writeDeltas(c.pending); err != nil {
+			return err
+		}
+		c.pending = make(map[historyKey]counterDelta)
+	}
+	c.dropRotations()
+	return nil
+}
+
+// dropRotations сбрасывает статистику в памяти, следующие выборы загрузят ее из базы.
+func (c *cachedDatabase) dropRotations() {
+	c.mu.Lock()
+	c.rotations = make(map[rotationKey]*cachedRotation)
+	c.mu.Unlock()
+}
+
+// writePending записывает накопленные счетчики в базу, не сбрасывая кэш: статистика в памяти
+// уже учитывает их, поэтому выборы продолжаются без перезагрузки и без ожидания записи.
+func (c *cachedDatabase) writePending() error {
+	c.barrier.RLock()
+	defer c.barrier.RUnlock()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.pendingMu.Lock()
+	deltas := c.pending
+	c.pending = make(map[historyKey]counterDelta)
+	c.pendingMu.Unlock()
+	if len(deltas) == 0 {
+		return nil
+	}
+	if err := c.writeDeltas(deltas); err != nil {
+		// Незаписанные счетчики возвращаются к накопленным с тех пор
+		c.pendingMu.Lock()
+		for key, delta := range deltas {
+			pending := c.pending[key]
+			pending.displays += delta.displays
+			pending.clicks += delta.clicks
+			c.pending[key] = pending
+		}
+		c.pendingMu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (c *cachedDatabase) writeDeltas(deltas map[historyKey]counterDelta) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	query := `UPDATE "Statistic"
+	SET display_count = display_count + $1, click_count = click_count + $2
+	WHERE slot_id=$3 AND group_id=$4 AND banner_id=$5`
+	for key, delta := range deltas {
+		if _, err := tx.Exec(query, delta.displays, delta.clicks, key.slotID, key.groupID, key.bannerID); err != nil {
+			return err
+		}
+		if err := recordHistoryTx(tx, key, delta.displays, delta.clicks); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addDelta накапливает счетчики в часе, когда произошло событие.
+func (c *cachedDatabase) addDelta(statistic statisticKey, displays, clicks int) {
+	key := historyKey{statisticKey: statistic, bucket: hourBucket(c.options.currentTime())}
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	delta := c.pending[key]
+	delta.displays += displays
+	delta.clicks += clicks
+	c.pending[key] = delta
+}
+
+// rotation возвращает заблокированную статистику пары слот/группа, загружая ее при необходимости.
+func (c *cachedDatabase) rotation(slotID, groupID int) (*cachedRotation, error) {
+	key := rotationKey{slotID: slotID, groupID: groupID}
+	c.mu.Lock()
+	entry, ok := c.rotations[key]
+	if !ok {
+		entry = &cachedRotation{}
+		c.rotations[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	if entry.loaded {
+		return entry, nil
+	}
+	if err := c.load(entry, slotID, groupID); err != nil {
+		entry.mu.Unlock()
+		c.mu.Lock()
+		if c.rotations[key] == entry {
+			delete(c.rotations, key)
+		}
+		c.mu.Unlock()
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (c *cachedDatabase) load(entry *cachedRotation, slotID, groupID int) error {
+	if err := checkEntityIsExists(c.databaseImpl, "Groups", groupID); err != nil {
+		return err
+	}
+	slot, err := c.DatabaseGetSlot(slotID)
+	if err != nil {
+		return err
+	}
+	strategy, err := newSlotStrategy(slot.Strategy, c.options.random)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	current := c.options.currentTime()
+	rows, stats, err := selectRotationStatsTx(tx, current, slotID, groupID)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return ErrNotInRotation
+	}
+	banners := bannerIDs(rows)
+	if err := selectRotationHistoryTx(tx, current, slotID, groupID, banners, stats, historyHours(strategy)); err != nil {
+		return err
+	}
+	// Фиксируются строки статистики, созданные при первом обращении к группе
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	entry.strategy = strategy
+	entry.algorithm = slot.Strategy.Algorithm
+	entry.banners = banners
+	entry.rows = rows
+	entry.stats = stats
+	entry.loaded = true
+	return nil
+}
+
+func (c *cachedDatabase) DatabaseSelectBanners(slotID, groupID, count int, excluded []int,
+) (structures.SelectedBanners, error) {
+	if count < 1 {
+		return structures.SelectedBanners{}, ErrIncorrectCount
+	}
+	c.barrier.RLock()
+	defer c.barrier.RUnlock()
+
+	entry, err := c.rotation(slotID, groupID)
+	if err != nil {
+		return structures.SelectedBanners{}, err
+	}
+	defer entry.mu.Unlock()
+
+	indexes, err := selectDistinct(entry.strategy, entry.banners, entry.stats, excluded, count)
+	if err != nil {
+		return structures.SelectedBanners{}, err
+	}
+	for _, index := range indexes {
+		entry.stats[index].Displays++
+		countCurrentHour(&entry.stats[index], 1, 0)
+		c.addDelta(statisticKey{slotID: slotID, groupID: groupID, bannerID: entry.banners[index]}, 1, 0)
+	}
+	// Данные баннеров кэшируются вместе со статистикой, изменение баннера сбрасывает кэш
+	return selectedBanners(entry.rows, indexes, entry.algorithm), nil
+}
+
+func (c *cachedDatabase) DatabaseRegisterTransition(slotID, bannerID, groupID int) error {
+	return c.registerTransition(slotID, bannerID, groupID, nil, func() error {
+		return c.databaseImpl.DatabaseRegisterTransition(slotID, bannerID, groupID)
+	})
+}
+
+func (c *cachedDatabase) DatabaseRegisterImpressionTransition(impressionID string, expiresAt time.Time,
+	slotID, bannerID, groupID int,
+) error {
+	use := func() error {
+		return c.databaseImpl.useImpression(impressionID, expiresAt)
+	}
+	return c.registerTransition(slotID, bannerID, groupID, use, func() error {
+		return c.databaseImpl.DatabaseRegisterImpressionTransition(impressionID, expiresAt, slotID, bannerID, groupID)
+	})
+}
+
+// registerTransition засчитывает переход в статистике в памяти, если баннер есть в кэше,
+// иначе выполняет direct. Показ отмечается use под блокировкой записи кэша
+// после всех проверок, а увеличение счетчика в памяти уже не завершается ошибкой.
+func (c *cachedDatabase) registerTransition(slotID, bannerID, groupID int, use, direct func() error) error {
+	c.barrier.RLock()
+	defer c.barrier.RUnlock()
+
+	entry, err := c.rotation(slotID, groupID)
+	if errors.Is(err, ErrNotInRotation) {
+		return direct()
+	}
+	if err != nil {
+		return err
+	}
+	defer entry.mu.Unlock()
+
+	bannerIndex := entry.index(bannerID)
+	if bannerIndex == invalidID {
+		// Приостановленного баннера нет в кэше, переход записывается в базу напрямую
+		return direct()
+	}
+	if use != nil {
+		if err := use(); err != nil {
+			return err
+		}
+	}
+	entry.stats[bannerIndex].Clicks++
+	countCurrentHour(&entry.stats[bannerIndex], 0, 1)
+	c.addDelta(statisticKey{slotID: slotID, groupID: groupID, bannerID: bannerID}, 0, 1)
+	return nil
+}
+
+// modify выполняет изменение данных, влияющих на ротацию, предварительно сбросив кэш.
+// Если счетчики не удалось записать, они остаются накопленными до следующего сброса,
+// а изменение выполняется: сбой записи статистики не должен блокировать управление ротацией.
+func (c *cachedDatabase) modify(operation func() error) error {
+	c.barrier.Lock()
+	defer c.barrier.Unlock()
+	if err := c.flushLocked(); err != nil {
+		log.Println("statistics flush:", err)
+		c.dropRotations()
+	}
+	return operation()
+}
+
+func (c *cachedDatabase) DatabaseAddToRotation(bannerID, slotID int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseAddToRotation(bannerID, slotID)
+	})
+}
+
+func (c *cachedDatabase) DatabaseAddToRotationWith(bannerID, slotID int,
+	flight structures.Flight, priority structures.Priority,
+) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseAddToRotationWith(bannerID, slotID, flight, priority)
+	})
+}
+
+func (c *cachedDatabase) DatabaseDeleteFromRotation(bannerID, slotID int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseDeleteFromRotation(bannerID, slotID)
+	})
+}
+
+func (c *cachedDatabase) DatabaseDeleteBanner(id int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseDeleteBanner(id)
+	})
+}
+
+func (c *cachedDatabase) DatabaseDeleteSlot(id int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseDeleteSlot(id)
+	})
+}
+
+func (c *cachedDatabase) DatabaseDeleteGroup(id int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseDeleteGroup(id)
+	})
+}
+
+func (c *cachedDatabase) DatabaseUpdateBanner(entity structures.Banner) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseUpdateBanner(entity)
+	})
+}
+
+func (c *cachedDatabase) DatabasePauseBanner(bannerID, slotID int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabasePauseBanner(bannerID, slotID)
+	})
+}
+
+func (c *cachedDatabase) DatabaseResumeBanner(bannerID, slotID int) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseResumeBanner(bannerID, slotID)
+	})
+}
+
+func (c *cachedDatabase) DatabaseSetRotationFlight(bannerID, slotID int, flight structures.Flight) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseSetRotationFlight(bannerID, slotID, flight)
+	})
+}
+
+func (c *cachedDatabase) DatabaseSetRotationPriority(bannerID, slotID int, priority structures.Priority) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseSetRotationPriority(bannerID, slotID, priority)
+	})
+}
+
+func (c *cachedDatabase) DatabaseUpdateSlot(entity structures.Slot) error {
+	return c.modify(func() error {
+		return c.databaseImpl.DatabaseUpdateSlot(entity)
+	})
+}
+
+// DatabaseGetStatistics записывает накопленные счетчики, чтобы статистика была актуальной.
+func (c *cachedDatabase) DatabaseGetStatistics(filter structures.StatisticFilter) ([]structures.Statistic, error) {
+	if err := c.writePending(); err != nil {
+		return nil, err
+	}
+	return c.databaseImpl.DatabaseGetStatistics(filter)
+}
+
+func (c *cachedDatabase) DatabaseGetStatisticsHistory(filter structures.HistoryFilter) (
+	[]structures.HistoryPoint, error,
+) {
+	if err := c.writePending(); err != nil {
+		return nil, err
+	}
+	return c.databaseImpl.DatabaseGetStatisticsHistory(filter)
+}
+
+func (c *cachedDatabase) DatabaseGetRotationMembers(filter structures.RotationFilter) (
+	[]structures.RotationMember, error,
+) {
+	return c.rotationMembers(filter, c.DatabaseGetStatistics)
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/stretchr/testify/require"
+)
+
+func sumStatistic(d *databaseImpl, slotID, groupID int) (int, int) {
+	displays, clicks := 0, 0
+	_ = d.db.QueryRow(`SELECT coalesce(sum(display_count), 0), coalesce(sum(click_count), 0)
+	FROM "Statistic" WHERE slot_id=$1 AND group_id=$2`, slotID, groupID).Scan(&displays, &clicks)
+	return displays, clicks
+}
+
+func TestCachedSelectFromRotation(t *testing.T) {
+	c := NewCachedDatabase(time.Hour).(*cachedDatabase)
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, err := c.DatabaseConnect(config)
+	require.NoError(t, err)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(*c.databaseImpl)
+
+	t.Run("select from memory and flush", func(t *testing.T) {
+		_, _ = c.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		_ = c.DatabaseAddToRotation(1, 1)
+		_ = c.DatabaseAddToRotation(2, 1)
+
+		bannerID, err := SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 1)
+		bannerID, err = SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 2)
+		require.NoError(t, c.DatabaseRegisterTransition(1, 2, 1))
+
+		displays, clicks := sumStatistic(c.databaseImpl, 1, 1)
+		require.Equal(t, displays, 0)
+		require.Equal(t, clicks, 0)
+
+		require.NoError(t, c.flush())
+		displays, clicks = sumStatistic(c.databaseImpl, 1, 1)
+		require.Equal(t, displays, 2)
+		require.Equal(t, clicks, 1)
+	})
+
+	t.Run("statistics read keeps cache", func(t *testing.T) {
+		_, _ = c.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		_ = c.DatabaseAddToRotation(1, 1)
+		_, err := SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+
+		statistics, err := c.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 1})
+		require.NoError(t, err)
+		require.Len(t, statistics, 1)
+		require.Equal(t, 1, statistics[0].Displays)
+		c.mu.Lock()
+		require.Contains(t, c.rotations, rotationKey{slotID: 1, groupID: 1})
+		c.mu.Unlock()
+
+		// Записанные счетчики не учитываются повторно при следующем сбросе
+		_, err = SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+		require.NoError(t, c.flush())
+		displays, _ := sumStatistic(c.databaseImpl, 1, 1)
+		require.Equal(t, displays, 2)
+	})
+
+	t.Run("rotation changes reset cache", func(t *testing.T) {
+		_, _ = c.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		_ = c.DatabaseAddToRotation(1, 1)
+		for i := 0; i < 5; i++ {
+			_, err := SelectFromRotation(c, 1, 1)
+			require.NoError(t, err)
+		}
+		require.NoError(t, c.DatabaseAddToRotation(3, 1))
+		displays, _ := sumStatistic(c.databaseImpl, 1, 1)
+		require.Equal(t, displays, 5)
+
+		bannerID, err := SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 3)
+	})
+
+	t.Run("failed flush does not block rotation changes", func(t *testing.T) {
+		_, _ = c.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		_ = c.DatabaseAddToRotation(1, 1)
+		_, err := SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+
+		// Счетчик, выходящий за пределы integer, не записывается в базу
+		var key historyKey
+		c.pendingMu.Lock()
+		pending := len(c.pending)
+		for pendingKey := range c.pending {
+			key = pendingKey
+		}
+		c.pending[key] = counterDelta{displays: math.MaxInt32 + 1}
+		c.pendingMu.Unlock()
+		require.Equal(t, 1, pending)
+		require.Error(t, c.flush())
+
+		require.NoError(t, c.DatabaseAddToRotation(2, 1))
+		members, err := c.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+		require.NoError(t, err)
+		require.Len(t, members, 2)
+		c.pendingMu.Lock()
+		kept := c.pending[key]
+		c.pending[key] = counterDelta{displays: 1}
+		c.pendingMu.Unlock()
+		require.Equal(t, counterDelta{displays: math.MaxInt32 + 1}, kept)
+
+		// Накопленные счетчики записываются при следующем сбросе
+		require.NoError(t, c.flush())
+		displays, _ := sumStatistic(c.databaseImpl, 1, 1)
+		require.Equal(t, displays, 1)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _ = c.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		_, err := SelectFromRotation(c, 1, 100)
+		require.ErrorIs(t, err, ErrNotExist)
+		_, err = SelectFromRotation(c, 100, 1)
+		require.ErrorIs(t, err, ErrNotExist)
+		_, err = SelectFromRotation(c, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+
+		_ = c.DatabaseAddToRotation(1, 1)
+		require.ErrorIs(t, c.DatabaseRegisterTransition(1, 2, 1), ErrNotInRotation)
+		require.ErrorIs(t, c.DatabaseRegisterTransition(1, 100, 1), ErrNotExist)
+	})
+}
+
+func TestCachedCloseFlushes(t *testing.T) {
+	c := NewCachedDatabase(time.Hour).(*cachedDatabase)
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, err := c.DatabaseConnect(config)
+	require.NoError(t, err)
+	setTestData(*c.databaseImpl)
+	_, _ = c.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	_ = c.DatabaseAddToRotation(1, 1)
+	for i := 0; i < 3; i++ {
+		_, err := SelectFromRotation(c, 1, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, closeConnection())
+
+	d := databaseImpl{db: nil}
+	closeConnection, _ = d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	displays, _ := sumStatistic(&d, 1, 1)
+	require.Equal(t, displays, 3)
+}
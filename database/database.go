@@ -3,9 +3,12 @@ package database
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/SergeyTyurin/banner-rotation/configs"
 	"github.com/SergeyTyurin/banner-rotation/structures"
@@ -13,10 +16,23 @@ import (
 )
 
 var (
-	ErrNilConfig         = errors.New("config is nil")
-	ErrNotExist          = errors.New("entity not exists in database")
-	ErrNotInRotation     = errors.New("entities not in rotation")
-	ErrAlreadyInRotation = errors.New("entities already in rotation")
+	ErrNilConfig            = errors.New("config is nil")
+	ErrNotExist             = errors.New("entity not exists in database")
+	ErrNotInRotation        = errors.New("entities not in rotation")
+	ErrAlreadyInRotation    = errors.New("entities already in rotation")
+	ErrIncorrectStrategy    = errors.New("incorrect slot strategy")
+	ErrUnknownBackend       = errors.New("unknown statistics backend")
+	ErrIncorrectMigrations  = errors.New("incorrect migrations")
+	ErrIncorrectGranularity = errors.New("incorrect history granularity")
+	ErrIncorrectListParams  = errors.New("incorrect list params")
+	ErrIncorrectEntity      = errors.New("incorrect entity fields")
+	ErrIncorrectFlight      = errors.New("incorrect flight period")
+	ErrIncorrectPriority    = errors.New("incorrect rotation priority")
+	ErrIncorrectCount       = errors.New("incorrect banners count")
+	ErrImpressionUsed       = errors.New("impression already used")
+	ErrBannerArchived       = errors.New("banner archived")
+	// ErrAllExcluded - в ротации есть активные баннеры, но все они исключены из выбора.
+	ErrAllExcluded = fmt.Errorf("%w: all banners excluded", ErrNotInRotation)
 )
 
 const invalidID = -1
@@ -28,6 +44,10 @@ type Database interface {
 	DatabaseGetSlot(id int) (structures.Slot, error)
 	DatabaseGetGroup(id int) (structures.Group, error)
 
+	DatabaseGetBanners(params structures.ListParams) ([]structures.Banner, error)
+	DatabaseGetSlots(params structures.ListParams) ([]structures.Slot, error)
+	DatabaseGetGroups(params structures.ListParams) ([]structures.Group, error)
+
 	DatabaseDeleteBanner(id int) error
 	DatabaseDeleteSlot(id int) error
 	DatabaseDeleteGroup(id int) error
@@ -36,25 +56,46 @@ type Database interface {
 	DatabaseCreateSlot(structures.Slot) (structures.Slot, error)
 	DatabaseCreateGroup(structures.Group) (structures.Group, error)
 
+	// Обновление заменяет все поля сущности, частичное обновление выполняет обработчик запроса.
 	DatabaseUpdateBanner(structures.Banner) error
 	DatabaseUpdateSlot(structures.Slot) error
 	DatabaseUpdateGroup(structures.Group) error
 
 	DatabaseAddToRotation(bannerID, slotID int) error
+	// Добавление в ротацию сразу с периодом показов и приоритетом одной операцией.
+	DatabaseAddToRotationWith(bannerID, slotID int, flight structures.Flight, priority structures.Priority) error
 	DatabaseDeleteFromRotation(bannerID, slotID int) error
-	DatabaseSelectFromRotation(slotID, groupID int) (bannerID int, err error)
 	DatabaseRegisterTransition(slotID, bannerID, groupID int) error
+	DatabaseGetRotationMembers(filter structures.RotationFilter) ([]structures.RotationMember, error)
+	// Приостановленный баннер не выбирается для показа, но сохраняет статистику.
+	// Нулевой slotID означает все слоты, архивный баннер глобально не приостанавливается и не возобновляется.
+	DatabasePauseBanner(bannerID, slotID int) error
+	DatabaseResumeBanner(bannerID, slotID int) error
+	// Баннер выбирается для показа только в течение периода показов в ротации слота.
+	DatabaseSetRotationFlight(bannerID, slotID int, flight structures.Flight) error
+	// Сумма гарантированных долей баннеров слота не может превышать 1.
+	DatabaseSetRotationPriority(bannerID, slotID int, priority structures.Priority) error
+	// Выбор до count разных баннеров без учета баннеров excluded, например достигших ограничения
+	// частоты показов пользователю. Баннеры выбираются так, как если бы они выбирались отдельными
+	// запросами подряд, показ учитывается каждому. Вместе с баннерами возвращается алгоритм слота.
+	DatabaseSelectBanners(slotID, groupID, count int, excluded []int) (structures.SelectedBanners, error)
+	// Переход по показу: показ отмечается использованным только вместе с учетом перехода,
+	// показ подтверждает переход только один раз, запись о нем хранится до expiresAt.
+	DatabaseRegisterImpressionTransition(impressionID string, expiresAt time.Time, slotID, bannerID, groupID int) error
+
+	DatabaseGetStatistics(filter structures.StatisticFilter) ([]structures.Statistic, error)
+	DatabaseGetStatisticsHistory(filter structures.HistoryFilter) ([]structures.HistoryPoint, error)
 }
 
 type databaseImpl struct {
-	db *sql.DB
+	db      *sql.DB
+	options options
 }
 
-func (di *databaseImpl) DatabaseConnect(config configs.DBConnectionConfig) (func() error, error) {
+func openDB(config configs.DBConnectionConfig) (*sql.DB, error) {
 	if config == nil {
 		return nil, ErrNilConfig
 	}
-	var connectionError error
 	url := config.URL()
 	url = strings.ReplaceAll(url, "{host}", config.Host())
 	url = strings.ReplaceAll(url, "{port}", strconv.Itoa(config.Port()))
@@ -62,7 +103,20 @@ func (di *databaseImpl) DatabaseConnect(config configs.DBConnectionConfig) (func
 	url = strings.ReplaceAll(url, "{password}", os.Getenv("DB_PASSWORD"))
 	url = strings.ReplaceAll(url, "{dbname}", config.DatabaseName())
 
-	di.db, connectionError = sql.Open("pgx", url)
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func (di *databaseImpl) DatabaseConnect(config configs.DBConnectionConfig) (func() error, error) {
+	var connectionError error
+	di.db, connectionError = openDB(config)
 	if connectionError != nil {
 		return nil, connectionError
 	}
@@ -70,17 +124,55 @@ func (di *databaseImpl) DatabaseConnect(config configs.DBConnectionConfig) (func
 		return di.db.Close()
 	}
 
-	if err := di.db.Ping(); err != nil {
-		if err := closeConnect(); err != nil {
-			return nil, err
-		}
+	// Схема приводится к последней версии при каждом подключении
+	if err := migrateUp(di.db); err != nil {
+		_ = closeConnect()
 		return nil, err
 	}
 	return closeConnect, nil
 }
 
-func NewDatabase() Database {
-	return &databaseImpl{db: nil}
+// Migrate применяет (up) или откатывает (down) миграции схемы.
+// steps ограничивает число откатываемых миграций, для up не используется.
+func Migrate(config configs.DBConnectionConfig, direction string, steps int) error {
+	db, err := openDB(config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch direction {
+	case "up":
+		return migrateUp(db)
+	case "down":
+		if steps <= 0 {
+			steps = 1
+		}
+		return migrateDown(db, steps)
+	default:
+		return fmt.Errorf("%w: unknown direction %q", ErrIncorrectMigrations, direction)
+	}
+}
+
+func NewDatabase(opts ...Option) Database {
+	return &databaseImpl{db: nil, options: newOptions(opts)}
+}
+
+// NewStatisticsDatabase создает базу данных с хранилищем статистики ротации из конфигурации.
+func NewStatisticsDatabase(config configs.StatisticsConfig, opts ...Option) (Database, error) {
+	if config == nil {
+		return NewDatabase(opts...), nil
+	}
+	switch config.Backend() {
+	case "", "postgres":
+		return NewDatabase(opts...), nil
+	case "cache":
+		return NewCachedDatabase(config.FlushInterval(), opts...), nil
+	case "redis":
+		return NewRedisDatabase(net.JoinHostPort(config.Host(), strconv.Itoa(config.Port())), opts...), nil
+	default:
+		return nil, ErrUnknownBackend
+	}
 }
 
 func checkEntityIsExists(d *databaseImpl, tablename string, id int) error {
@@ -4,9 +4,13 @@ import (
 	"github.com/SergeyTyurin/banner-rotation/structures"
 )
 
-func (d *databaseImpl) DatabaseGetGroups() ([]structures.Group, error) {
-	query := `SELECT id, info FROM "Groups"`
-	rows, err := d.db.Query(query)
+func (d *databaseImpl) DatabaseGetGroups(params structures.ListParams) ([]structures.Group, error) {
+	if err := validateListParams(&params); err != nil {
+		return nil, err
+	}
+	condition, args := listCondition(params)
+	query := `SELECT id, info, description FROM "Groups" ` + condition
+	rows, err := d.db.Query(query, args...)
 	if err != nil || rows.Err() != nil {
 		return nil, err
 	}
@@ -14,25 +18,24 @@ func (d *databaseImpl) DatabaseGetGroups() ([]structures.Group, error) {
 
 	groups := make([]structures.Group, 0)
 	for rows.Next() {
-		var id int
-		var info string
-		if err := rows.Scan(&id, &info); err != nil {
+		var group structures.Group
+		if err := rows.Scan(&group.ID, &group.Info, &group.Description); err != nil {
 			return nil, err
 		}
-		groups = append(groups, structures.Group{ID: id, Info: info})
+		groups = append(groups, group)
 	}
 	return groups, nil
 }
 
 func (d *databaseImpl) DatabaseGetGroup(id int) (structures.Group, error) {
-	query := `SELECT info FROM "Groups" WHERE id = $1`
+	query := `SELECT info, description FROM "Groups" WHERE id = $1`
 	row := d.db.QueryRow(query, id)
 
-	var info string
-	if err := row.Scan(&info); err != nil {
+	group := structures.Group{ID: id}
+	if err := row.Scan(&group.Info, &group.Description); err != nil {
 		return structures.Group{ID: invalidID}, ErrNotExist
 	}
-	return structures.Group{ID: id, Info: info}, nil
+	return group, nil
 }
 
 func (d *databaseImpl) DatabaseDeleteGroup(id int) error {
@@ -68,7 +71,7 @@ func (d *databaseImpl) DatabaseDeleteGroup(id int) error {
 }
 
 func (d *databaseImpl) DatabaseCreateGroup(entity structures.Group) (structures.Group, error) {
-	query := `INSERT INTO "Groups" (info) VALUES($1)
+	query := `INSERT INTO "Groups" (info, description) VALUES($1, $2)
 	RETURNING id`
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -78,7 +81,7 @@ func (d *databaseImpl) DatabaseCreateGroup(entity structures.Group) (structures.
 		_ = tx.Rollback()
 	}()
 
-	row := tx.QueryRow(query, entity.Info)
+	row := tx.QueryRow(query, entity.Info, entity.Description)
 	id := invalidID
 	if err := row.Scan(&id); err != nil {
 		return structures.Group{ID: invalidID}, err
@@ -87,7 +90,8 @@ func (d *databaseImpl) DatabaseCreateGroup(entity structures.Group) (structures.
 	if err := tx.Commit(); err != nil {
 		return structures.Group{ID: invalidID}, err
 	}
-	return structures.Group{ID: id, Info: entity.Info}, nil
+	entity.ID = id
+	return entity, nil
 }
 
 func (d *databaseImpl) DatabaseUpdateGroup(entity structures.Group) error {
@@ -95,8 +99,8 @@ func (d *databaseImpl) DatabaseUpdateGroup(entity structures.Group) error {
 		return err
 	}
 	query := `UPDATE "Groups"
-	SET info = $1
-	WHERE id = $2`
+	SET info = $1, description = $2
+	WHERE id = $3`
 
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -106,7 +110,7 @@ func (d *databaseImpl) DatabaseUpdateGroup(entity structures.Group) error {
 		_ = tx.Rollback()
 	}()
 
-	res, err := tx.Exec(query, entity.Info, entity.ID)
+	res, err := tx.Exec(query, entity.Info, entity.Description, entity.ID)
 	if err != nil {
 		return err
 	}
@@ -10,7 +10,7 @@ import (
 )
 
 func TestCreateGroup(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -29,7 +29,7 @@ func TestCreateGroup(t *testing.T) {
 }
 
 func TestGetGroups(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -44,7 +44,7 @@ func TestGetGroups(t *testing.T) {
 			_, _ = d.DatabaseCreateGroup(group)
 		}
 
-		groups, err := d.DatabaseGetGroups()
+		groups, err := d.DatabaseGetGroups(structures.ListParams{})
 		require.NoError(t, err)
 		require.Equal(t, len(groups), count)
 	})
@@ -64,7 +64,7 @@ func TestGetGroups(t *testing.T) {
 
 	t.Run("get from empty", func(t *testing.T) {
 		_, _ = d.db.Exec(`TRUNCATE TABLE "Groups" RESTART IDENTITY CASCADE`)
-		groups, err := d.DatabaseGetGroups()
+		groups, err := d.DatabaseGetGroups(structures.ListParams{})
 		require.NoError(t, err)
 		require.Empty(t, groups)
 
@@ -76,7 +76,7 @@ func TestGetGroups(t *testing.T) {
 }
 
 func TestUpdateGroup(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -104,7 +104,7 @@ func TestUpdateGroup(t *testing.T) {
 }
 
 func TestDeleteGroup(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -125,7 +125,7 @@ func TestDeleteGroup(t *testing.T) {
 		err := d.DatabaseDeleteGroup(newGroup.ID)
 		require.NoError(t, err)
 
-		groups, _ := d.DatabaseGetGroups()
+		groups, _ := d.DatabaseGetGroups(structures.ListParams{})
 		require.Empty(t, groups)
 	})
 }
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+const hourSeconds = int64(time.Hour / time.Second)
+
+// historyKey - счетчики баннера за час, начинающийся в bucket (unix-время UTC).
+type historyKey struct {
+	statisticKey
+	bucket int64
+}
+
+// hourBucket возвращает начало часа, в который попадает момент t.
+func hourBucket(t time.Time) int64 {
+	return t.UTC().Truncate(time.Hour).Unix()
+}
+
+// historyHours возвращает, за сколько последних часов алгоритму нужна почасовая статистика.
+func historyHours(strategy bannerselector.Strategy) int {
+	if s, ok := strategy.(bannerselector.HistoryStrategy); ok {
+		return s.HistoryHours()
+	}
+	return 0
+}
+
+// historyBuckets возвращает начала hours последних до момента t часов, индекс равен давности часа.
+func historyBuckets(t time.Time, hours int) []int64 {
+	current := hourBucket(t)
+	buckets := make([]int64, hours)
+	for age := range buckets {
+		buckets[age] = current - int64(age)*hourSeconds
+	}
+	return buckets
+}
+
+// prepareHistory отмечает, что статистика содержит историю, даже если часов с показами нет:
+// без этого алгоритм считает все показы сделанными в текущем часе.
+func prepareHistory(stats []bannerselector.ArmStats) {
+	for i := range stats {
+		stats[i].History = make([]bannerselector.BucketStats, 0)
+	}
+}
+
+// addBucket добавляет в историю баннера ненулевые счетчики часа давностью age.
+func addBucket(arm *bannerselector.ArmStats, age, displays, clicks int) {
+	if displays == 0 && clicks == 0 {
+		return
+	}
+	arm.History = append(arm.History, bannerselector.BucketStats{Age: age, Displays: displays, Clicks: clicks})
+}
+
+// countCurrentHour учитывает событие в загруженной истории баннера.
+func countCurrentHour(arm *bannerselector.ArmStats, displays, clicks int) {
+	if arm.History == nil {
+		return
+	}
+	for i := range arm.History {
+		if arm.History[i].Age == 0 {
+			arm.History[i].Displays += displays
+			arm.History[i].Clicks += clicks
+			return
+		}
+	}
+	addBucket(arm, 0, displays, clicks)
+}
+
+func validateHistoryFilter(filter *structures.HistoryFilter) error {
+	switch filter.Granularity {
+	case "":
+		filter.Granularity = structures.GranularityHour
+	case structures.GranularityHour, structures.GranularityDay:
+	default:
+		return ErrIncorrectGranularity
+	}
+	return nil
+}
+
+// matchHistory проверяет, подходит ли час bucket баннера под фильтр.
+func matchHistory(filter structures.HistoryFilter, key statisticKey, bucket int64) bool {
+	switch {
+	case filter.SlotID != 0 && key.slotID != filter.SlotID,
+		filter.GroupID != 0 && key.groupID != filter.GroupID,
+		filter.BannerID != 0 && key.bannerID != filter.BannerID,
+		!filter.From.IsZero() && bucket < filter.From.Unix(),
+		!filter.To.IsZero() && bucket >= filter.To.Unix():
+		return false
+	}
+	return true
+}
+
+// aggregateHistory объединяет часовые счетчики до нужной детализации и вычисляет CTR.
+func aggregateHistory(points []structures.HistoryPoint, granularity string) []structures.HistoryPoint {
+	type pointKey struct {
+		statisticKey
+		time int64
+	}
+	sums := make(map[pointKey]structures.HistoryPoint)
+	for _, point := range points {
+		point.Time = point.Time.UTC()
+		if granularity == structures.GranularityDay {
+			point.Time = point.Time.Truncate(24 * time.Hour)
+		}
+		key := pointKey{
+			statisticKey: statisticKey{slotID: point.SlotID, groupID: point.GroupID, bannerID: point.BannerID},
+			time:         point.Time.Unix(),
+		}
+		sum, ok := sums[key]
+		if !ok {
+			sum = point
+			sum.Displays, sum.Clicks = 0, 0
+		}
+		sum.Displays += point.Displays
+		sum.Clicks += point.Clicks
+		sums[key] = sum
+	}
+
+	result := make([]structures.HistoryPoint, 0, len(sums))
+	for _, point := range sums {
+		point.CTR = bannerselector.ArmStats{Displays: point.Displays, Clicks: point.Clicks}.CTR()
+		result = append(result, point)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		switch {
+		case a.SlotID != b.SlotID:
+			return a.SlotID < b.SlotID
+		case a.GroupID != b.GroupID:
+			return a.GroupID < b.GroupID
+		case a.BannerID != b.BannerID:
+			return a.BannerID < b.BannerID
+		}
+		return a.Time.Before(b.Time)
+	})
+	return result
+}
+
+func recordHistoryTx(tx *sql.Tx, key historyKey, displays, clicks int) error {
+	// Счетчики баннера, удаленного из ротации до записи, отбрасываются вместе с его статистикой
+	query := `INSERT INTO "StatisticHistory"(slot_id, group_id, banner_id, bucket, display_count, click_count)
+	SELECT $1::integer, $2::integer, $3::integer, $4::timestamptz, $5::integer, $6::integer
+	WHERE EXISTS (SELECT 1 FROM "Rotation" WHERE slot_id = $1 AND banner_id = $3)
+	AND EXISTS (SELECT 1 FROM "Groups" WHERE id = $2)
+	ON CONFLICT (slot_id, group_id, banner_id, bucket) DO UPDATE
+	SET display_count = "StatisticHistory".display_count + EXCLUDED.display_count,
+		click_count = "StatisticHistory".click_count + EXCLUDED.click_count`
+	_, err := tx.Exec(query, key.slotID, key.groupID, key.bannerID,
+		time.Unix(key.bucket, 0).UTC(), displays, clicks)
+	return err
+}
+
+// selectRotationHistoryTx загружает историю баннеров пары слот/группа за hours последних до момента t часов.
+func selectRotationHistoryTx(tx *sql.Tx, t time.Time, slotID, groupID int,
+	banners []int, stats []bannerselector.ArmStats, hours int,
+) error {
+	if hours <= 0 {
+		return nil
+	}
+	prepareHistory(stats)
+	buckets := historyBuckets(t, hours)
+	query := `SELECT banner_id, bucket, display_count, click_count FROM "StatisticHistory"
+	WHERE slot_id=$1 AND group_id=$2 AND bucket >= $3`
+	rows, err := tx.Query(query, slotID, groupID, time.Unix(buckets[hours-1], 0).UTC())
+	if err != nil || rows.Err() != nil {
+		return err
+	}
+	defer rows.Close()
+
+	indexes := make(map[int]int, len(banners))
+	for i, bannerID := range banners {
+		indexes[bannerID] = i
+	}
+	for rows.Next() {
+		var bannerID, displays, clicks int
+		var bucket time.Time
+		if err := rows.Scan(&bannerID, &bucket, &displays, &clicks); err != nil {
+			return err
+		}
+		i, ok := indexes[bannerID]
+		if !ok {
+			continue
+		}
+		addBucket(&stats[i], int((buckets[0]-bucket.Unix())/hourSeconds), displays, clicks)
+	}
+	return nil
+}
+
+func (d *databaseImpl) DatabaseGetStatisticsHistory(filter structures.HistoryFilter) (
+	[]structures.HistoryPoint, error,
+) {
+	if err := validateHistoryFilter(&filter); err != nil {
+		return nil, err
+	}
+	query := `SELECT slot_id, group_id, banner_id, bucket, display_count, click_count
+	FROM "StatisticHistory"
+	WHERE ($1 = 0 OR slot_id = $1) AND ($2 = 0 OR group_id = $2) AND ($3 = 0 OR banner_id = $3)
+		AND ($4::timestamptz IS NULL OR bucket >= $4) AND ($5::timestamptz IS NULL OR bucket < $5)`
+	rows, err := d.db.Query(query, filter.SlotID, filter.GroupID, filter.BannerID,
+		nullTime(filter.From), nullTime(filter.To))
+	if err != nil || rows.Err() != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]structures.HistoryPoint, 0)
+	for rows.Next() {
+		var point structures.HistoryPoint
+		if err := rows.Scan(&point.SlotID, &point.GroupID, &point.BannerID, &point.Time,
+			&point.Displays, &point.Clicks); err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return aggregateHistory(points, filter.Granularity), nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
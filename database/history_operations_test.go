@@ -0,0 +1,164 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/stretchr/testify/require"
+)
+
+// ptr возвращает указатель на копию значения.
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestAggregateHistory(t *testing.T) {
+	day := time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)
+	points := []structures.HistoryPoint{
+		{SlotID: 1, GroupID: 1, BannerID: 1, Time: day.Add(25 * time.Hour), Displays: 4, Clicks: 1},
+		{SlotID: 1, GroupID: 1, BannerID: 1, Time: day.Add(time.Hour), Displays: 2, Clicks: 1},
+		{SlotID: 1, GroupID: 1, BannerID: 1, Time: day.Add(2 * time.Hour), Displays: 2, Clicks: 0},
+	}
+
+	t.Run("hour", func(t *testing.T) {
+		hourly := aggregateHistory(points, structures.GranularityHour)
+		require.Len(t, hourly, 3)
+		require.Equal(t, day.Add(time.Hour), hourly[0].Time)
+		require.InDelta(t, 0.5, hourly[0].CTR, 1e-9)
+		require.Equal(t, day.Add(25*time.Hour), hourly[2].Time)
+	})
+
+	t.Run("day", func(t *testing.T) {
+		daily := aggregateHistory(points, structures.GranularityDay)
+		require.Len(t, daily, 2)
+		require.Equal(t, day, daily[0].Time)
+		require.Equal(t, 4, daily[0].Displays)
+		require.Equal(t, 1, daily[0].Clicks)
+		require.InDelta(t, 0.25, daily[0].CTR, 1e-9)
+		require.Equal(t, day.Add(24*time.Hour), daily[1].Time)
+	})
+}
+
+func TestMemoryHistory(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	m := setMemoryTestData(t, WithClock(func() time.Time { return current }))
+	require.NoError(t, m.DatabaseAddToRotation(1, 1))
+
+	_, _ = SelectFromRotation(m, 1, 1)
+	require.NoError(t, m.DatabaseRegisterTransition(1, 1, 1))
+	current = current.Add(time.Hour)
+	_, _ = SelectFromRotation(m, 1, 1)
+	_, _ = SelectFromRotation(m, 1, 1)
+
+	history, err := m.DatabaseGetStatisticsHistory(structures.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), history[0].Time)
+	require.Equal(t, 1, history[0].Displays)
+	require.Equal(t, 1, history[0].Clicks)
+	require.Equal(t, 2, history[1].Displays)
+
+	history, err = m.DatabaseGetStatisticsHistory(structures.HistoryFilter{
+		From: time.Date(2023, 5, 1, 11, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+
+	history, err = m.DatabaseGetStatisticsHistory(structures.HistoryFilter{Granularity: structures.GranularityDay})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, 3, history[0].Displays)
+
+	_, err = m.DatabaseGetStatisticsHistory(structures.HistoryFilter{Granularity: "week"})
+	require.ErrorIs(t, err, ErrIncorrectGranularity)
+
+	require.NoError(t, m.DatabaseDeleteFromRotation(1, 1))
+	history, _ = m.DatabaseGetStatisticsHistory(structures.HistoryFilter{})
+	require.Empty(t, history)
+}
+
+func TestStatisticsHistory(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	d := databaseImpl{db: nil, options: newOptions([]Option{WithClock(func() time.Time { return current })})}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	_ = d.DatabaseAddToRotation(1, 1)
+
+	_, _ = SelectFromRotation(&d, 1, 1)
+	require.NoError(t, d.DatabaseRegisterTransition(1, 1, 1))
+	current = current.Add(time.Hour)
+	_, _ = SelectFromRotation(&d, 1, 1)
+
+	history, err := d.DatabaseGetStatisticsHistory(structures.HistoryFilter{
+		StatisticFilter: structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC), history[0].Time)
+	require.Equal(t, 1, history[0].Clicks)
+
+	history, err = d.DatabaseGetStatisticsHistory(structures.HistoryFilter{
+		To:          time.Date(2023, 5, 1, 11, 0, 0, 0, time.UTC),
+		Granularity: structures.GranularityDay,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, 1, history[0].Displays)
+}
+
+// checkSlidingWindowSelect проверяет, что выбор по скользящему окну в один час
+// учитывает только показы и переходы текущего часа.
+func checkSlidingWindowSelect(t *testing.T, d Database, current *time.Time) {
+	t.Helper()
+	slot, err := d.DatabaseCreateSlot(structures.Slot{
+		Info:     "windowed",
+		Strategy: structures.Strategy{Algorithm: bannerselector.SlidingWindowUCB, Window: ptr(1)},
+	})
+	require.NoError(t, err)
+	require.NoError(t, d.DatabaseAddToRotation(1, slot.ID))
+	require.NoError(t, d.DatabaseAddToRotation(2, slot.ID))
+
+	selectBanner := func(expected int) {
+		t.Helper()
+		bannerID, err := SelectFromRotation(d, slot.ID, 1)
+		require.NoError(t, err)
+		require.Equal(t, expected, bannerID)
+	}
+	selectBanner(1)
+	require.NoError(t, d.DatabaseRegisterTransition(slot.ID, 1, 1))
+	selectBanner(2)
+
+	// В новом часе оба баннера снова исследуются, переход первого уже не учитывается
+	*current = current.Add(time.Hour)
+	selectBanner(1)
+	selectBanner(2)
+	require.NoError(t, d.DatabaseRegisterTransition(slot.ID, 2, 1))
+	// По общей статистике баннеры равны и был бы выбран первый
+	selectBanner(2)
+}
+
+func TestMemorySlidingWindowSelect(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	checkSlidingWindowSelect(t, setMemoryTestData(t, WithClock(func() time.Time { return current })), &current)
+}
+
+func TestSlidingWindowSelect(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	d := databaseImpl{db: nil, options: newOptions([]Option{WithClock(func() time.Time { return current })})}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	checkSlidingWindowSelect(t, &d, &current)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// useImpression отмечает показ использованным в отдельной транзакции.
+func (d *databaseImpl) useImpression(impressionID string, expiresAt time.Time) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if err := useImpressionTx(tx, d.options.currentTime(), impressionID, expiresAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// useImpressionTx отмечает показ использованным. Конкурентная транзакция с тем же показом
+// ожидает завершения текущей и получает ErrImpressionUsed, только если текущая зафиксирована.
+func useImpressionTx(tx *sql.Tx, current time.Time, impressionID string, expiresAt time.Time) error {
+	// Истекшие показы уже не принимаются проверкой токена, их записи не нужны
+	if _, err := tx.Exec(`DELETE FROM "Impressions" WHERE expires_at <= $1`, current); err != nil {
+		return err
+	}
+	query := `INSERT INTO "Impressions" (id, expires_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`
+	result, err := tx.Exec(query, impressionID, expiresAt)
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return ErrImpressionUsed
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpressionExpiration(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	d := databaseImpl{db: nil, options: newOptions([]Option{WithClock(func() time.Time { return current })})}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Impressions"`)
+	_ = d.DatabaseAddToRotation(1, 1)
+	use := func(impressionID string, expiresAt time.Time) error {
+		return d.DatabaseRegisterImpressionTransition(impressionID, expiresAt, 1, 1, 1)
+	}
+
+	require.NoError(t, use("first", current.Add(time.Hour)))
+	require.ErrorIs(t, use("first", current.Add(time.Hour)), ErrImpressionUsed)
+	require.NoError(t, use("second", current.Add(time.Hour)))
+
+	// записи истекших показов удаляются
+	current = current.Add(time.Hour)
+	require.NoError(t, use("third", current.Add(time.Hour)))
+	count := 0
+	require.NoError(t, d.db.QueryRow(`SELECT count(*) FROM "Impressions"`).Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func TestRegisterImpressionTransition(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Impressions"`)
+	_ = d.DatabaseAddToRotation(1, 1)
+	expiresAt := time.Now().Add(time.Hour)
+	clicks := func() int {
+		count := 0
+		_ = d.db.QueryRow(`SELECT click_count FROM "Statistic" WHERE slot_id=1 AND group_id=1 AND banner_id=1`).
+			Scan(&count)
+		return count
+	}
+
+	// переход по баннеру вне ротации не расходует показ
+	require.ErrorIs(t, d.DatabaseRegisterImpressionTransition("first", expiresAt, 1, 2, 1), ErrNotInRotation)
+	require.NoError(t, d.DatabaseRegisterImpressionTransition("first", expiresAt, 1, 1, 1))
+	require.Equal(t, 1, clicks())
+	require.ErrorIs(t, d.DatabaseRegisterImpressionTransition("first", expiresAt, 1, 1, 1), ErrImpressionUsed)
+	require.Equal(t, 1, clicks())
+}
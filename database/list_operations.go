@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+func validateListParams(params *structures.ListParams) error {
+	if params.Limit < 0 || params.Offset < 0 {
+		return ErrIncorrectListParams
+	}
+	switch params.Sort {
+	case "":
+		params.Sort = structures.SortByID
+	case structures.SortByID, structures.SortByInfo:
+	default:
+		return ErrIncorrectListParams
+	}
+	return nil
+}
+
+// listCondition возвращает условия отбора, сортировки и страницы списка и их аргументы.
+// Поле сортировки подставляется в запрос только после validateListParams.
+func listCondition(params structures.ListParams) (string, []interface{}) {
+	direction := "ASC"
+	if params.Desc {
+		direction = "DESC"
+	}
+	condition := fmt.Sprintf(`WHERE ($1 = '' OR strpos(lower(info), lower($1)) > 0)
+	ORDER BY %s %s, id %s
+	LIMIT $2 OFFSET $3`, params.Sort, direction, direction)
+	limit := sql.NullInt64{Int64: int64(params.Limit), Valid: params.Limit > 0}
+	return condition, []interface{}{params.Info, limit, params.Offset}
+}
+
+// listEntities отбирает, сортирует и разбивает на страницы сущности, хранящиеся в памяти.
+func listEntities[T any](entities map[int]T, params structures.ListParams,
+	info func(T) string,
+) ([]T, error) {
+	if err := validateListParams(&params); err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(entities))
+	for id, entity := range entities {
+		if strings.Contains(strings.ToLower(info(entity)), strings.ToLower(params.Info)) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		if params.Desc {
+			a, b = b, a
+		}
+		if params.Sort == structures.SortByInfo && info(entities[a]) != info(entities[b]) {
+			return info(entities[a]) < info(entities[b])
+		}
+		return a < b
+	})
+
+	if params.Offset > len(ids) {
+		params.Offset = len(ids)
+	}
+	ids = ids[params.Offset:]
+	if params.Limit > 0 && params.Limit < len(ids) {
+		ids = ids[:params.Limit]
+	}
+	list := make([]T, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, entities[id])
+	}
+	return list, nil
+}
@@ -0,0 +1,653 @@
+package database
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+// impressionSweepInterval - как часто удаляются записи истекших показов.
+const impressionSweepInterval = time.Minute
+
+// memoryDatabase - потокобезопасная реализация Database в памяти процесса
+// для тестов и локальной разработки. Данные теряются при остановке сервиса.
+type memoryDatabase struct {
+	mu sync.Mutex
+
+	banners map[int]structures.Banner
+	slots   map[int]structures.Slot
+	groups  map[int]structures.Group
+
+	rotation  map[rotationMemberKey]rotationMember
+	statistic map[statisticKey]bannerselector.ArmStats
+	history   map[historyKey]bannerselector.ArmStats
+	// impressions - использованные показы и сроки их действия,
+	// истекшие записи удаляются не чаще раза в impressionSweepInterval
+	impressions      map[string]time.Time
+	impressionsSwept time.Time
+
+	options options
+
+	lastBannerID int
+	lastSlotID   int
+	lastGroupID  int
+}
+
+type rotationMemberKey struct {
+	slotID   int
+	bannerID int
+}
+
+// rotationMember - баннер в ротации слота.
+type rotationMember struct {
+	weight  float64
+	share   float64
+	status  string
+	addedAt time.Time
+	flight  structures.Flight
+}
+
+func NewMemoryDatabase(opts ...Option) Database {
+	return &memoryDatabase{
+		options: newOptions(opts),
+
+		banners:   make(map[int]structures.Banner),
+		slots:     make(map[int]structures.Slot),
+		groups:    make(map[int]structures.Group),
+		rotation:  make(map[rotationMemberKey]rotationMember),
+		statistic: make(map[statisticKey]bannerselector.ArmStats),
+		history:   make(map[historyKey]bannerselector.ArmStats),
+
+		impressions: make(map[string]time.Time),
+	}
+}
+
+func (m *memoryDatabase) DatabaseConnect(_ configs.DBConnectionConfig) (func() error, error) {
+	return func() error {
+		return nil
+	}, nil
+}
+
+func (m *memoryDatabase) DatabaseGetBanners(params structures.ListParams) ([]structures.Banner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return listEntities(m.banners, params, func(entity structures.Banner) string {
+		return entity.Info
+	})
+}
+
+func (m *memoryDatabase) DatabaseGetSlots(params structures.ListParams) ([]structures.Slot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return listEntities(m.slots, params, func(entity structures.Slot) string {
+		return entity.Info
+	})
+}
+
+func (m *memoryDatabase) DatabaseGetGroups(params structures.ListParams) ([]structures.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return listEntities(m.groups, params, func(entity structures.Group) string {
+		return entity.Info
+	})
+}
+
+func (m *memoryDatabase) DatabaseGetBanner(id int) (structures.Banner, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	banner, ok := m.banners[id]
+	if !ok {
+		return structures.Banner{ID: invalidID}, ErrNotExist
+	}
+	return banner, nil
+}
+
+func (m *memoryDatabase) DatabaseGetSlot(id int) (structures.Slot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	slot, ok := m.slots[id]
+	if !ok {
+		return structures.Slot{ID: invalidID}, ErrNotExist
+	}
+	return slot, nil
+}
+
+func (m *memoryDatabase) DatabaseGetGroup(id int) (structures.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	group, ok := m.groups[id]
+	if !ok {
+		return structures.Group{ID: invalidID}, ErrNotExist
+	}
+	return group, nil
+}
+
+// deleteStatistic удаляет строки статистики и ее историю, для которых match возвращает true.
+func (m *memoryDatabase) deleteStatistic(match func(key statisticKey) bool) {
+	for key := range m.statistic {
+		if match(key) {
+			delete(m.statistic, key)
+		}
+	}
+	for key := range m.history {
+		if match(key.statisticKey) {
+			delete(m.history, key)
+		}
+	}
+}
+
+// addStatistic увеличивает счетчики баннера и его историю за текущий час.
+func (m *memoryDatabase) addStatistic(key statisticKey, displays, clicks int) {
+	arm := m.statistic[key]
+	arm.Displays += displays
+	arm.Clicks += clicks
+	m.statistic[key] = arm
+
+	bucket := historyKey{statisticKey: key, bucket: hourBucket(m.options.currentTime())}
+	arm = m.history[bucket]
+	arm.Displays += displays
+	arm.Clicks += clicks
+	m.history[bucket] = arm
+}
+
+// deleteRotation удаляет баннеры из ротации вместе с их статистикой.
+func (m *memoryDatabase) deleteRotation(match func(key rotationMemberKey) bool) {
+	for key := range m.rotation {
+		if match(key) {
+			delete(m.rotation, key)
+		}
+	}
+	m.deleteStatistic(func(key statisticKey) bool {
+		return match(rotationMemberKey{slotID: key.slotID, bannerID: key.bannerID})
+	})
+}
+
+func (m *memoryDatabase) DatabaseDeleteBanner(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.banners[id]; !ok {
+		return ErrNotExist
+	}
+	m.deleteRotation(func(key rotationMemberKey) bool {
+		return key.bannerID == id
+	})
+	delete(m.banners, id)
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseDeleteSlot(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.slots[id]; !ok {
+		return ErrNotExist
+	}
+	m.deleteRotation(func(key rotationMemberKey) bool {
+		return key.slotID == id
+	})
+	delete(m.slots, id)
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseDeleteGroup(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[id]; !ok {
+		return ErrNotExist
+	}
+	m.deleteStatistic(func(key statisticKey) bool {
+		return key.groupID == id
+	})
+	delete(m.groups, id)
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseCreateBanner(entity structures.Banner) (structures.Banner, error) {
+	if err := validateBanner(&entity); err != nil {
+		return structures.Banner{ID: invalidID}, err
+	}
+	if entity.Status == "" {
+		entity.Status = structures.BannerActive
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastBannerID++
+	entity.ID = m.lastBannerID
+	entity.CreatedAt = m.options.currentTime()
+	entity.UpdatedAt = entity.CreatedAt
+	m.banners[entity.ID] = entity
+	return entity, nil
+}
+
+func (m *memoryDatabase) DatabaseCreateSlot(entity structures.Slot) (structures.Slot, error) {
+	if err := validateSlot(&entity); err != nil {
+		return structures.Slot{ID: invalidID}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastSlotID++
+	entity.ID = m.lastSlotID
+	m.slots[entity.ID] = entity
+	return entity, nil
+}
+
+func (m *memoryDatabase) DatabaseCreateGroup(entity structures.Group) (structures.Group, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastGroupID++
+	entity.ID = m.lastGroupID
+	m.groups[entity.ID] = entity
+	return entity, nil
+}
+
+func (m *memoryDatabase) DatabaseUpdateBanner(entity structures.Banner) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	banner, ok := m.banners[entity.ID]
+	if !ok {
+		return ErrNotExist
+	}
+	if err := validateBanner(&entity); err != nil {
+		return err
+	}
+	if entity.Status == "" {
+		entity.Status = banner.Status
+	}
+	if banner.Status == structures.BannerArchived && entity.Status != structures.BannerArchived {
+		return ErrBannerArchived
+	}
+	entity.CreatedAt = banner.CreatedAt
+	entity.UpdatedAt = m.options.currentTime()
+	m.banners[entity.ID] = entity
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseUpdateSlot(entity structures.Slot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.slots[entity.ID]; !ok {
+		return ErrNotExist
+	}
+	if err := validateSlot(&entity); err != nil {
+		return err
+	}
+	m.slots[entity.ID] = entity
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseUpdateGroup(entity structures.Group) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[entity.ID]; !ok {
+		return ErrNotExist
+	}
+	m.groups[entity.ID] = entity
+	return nil
+}
+
+func (m *memoryDatabase) checkRotationEntities(bannerID, slotID int) error {
+	if _, ok := m.banners[bannerID]; !ok {
+		return ErrNotExist
+	}
+	if _, ok := m.slots[slotID]; !ok {
+		return ErrNotExist
+	}
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseAddToRotation(bannerID, slotID int) error {
+	return m.DatabaseAddToRotationWith(bannerID, slotID, structures.Flight{}, defaultPriority)
+}
+
+func (m *memoryDatabase) DatabaseAddToRotationWith(bannerID, slotID int,
+	flight structures.Flight, priority structures.Priority,
+) error {
+	if err := validateFlight(flight); err != nil {
+		return err
+	}
+	if err := validatePriority(priority); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkRotationEntities(bannerID, slotID); err != nil {
+		return err
+	}
+	key := rotationMemberKey{slotID: slotID, bannerID: bannerID}
+	if _, ok := m.rotation[key]; ok {
+		return ErrAlreadyInRotation
+	}
+	if err := checkShares(m.slotShares(key), priority); err != nil {
+		return err
+	}
+	m.rotation[key] = rotationMember{
+		weight:  priority.Weight,
+		share:   priority.Share,
+		status:  rotationActive,
+		addedAt: m.options.currentTime(),
+		flight:  flight,
+	}
+	return nil
+}
+
+// slotShares возвращает сумму гарантированных долей баннеров ротации слота, кроме key.
+func (m *memoryDatabase) slotShares(key rotationMemberKey) float64 {
+	var shares float64
+	for other, member := range m.rotation {
+		if other.slotID == key.slotID && other != key {
+			shares += member.share
+		}
+	}
+	return shares
+}
+
+func (m *memoryDatabase) DatabaseDeleteFromRotation(bannerID, slotID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkRotationEntities(bannerID, slotID); err != nil {
+		return err
+	}
+	if _, ok := m.rotation[rotationMemberKey{slotID: slotID, bannerID: bannerID}]; !ok {
+		return ErrNotInRotation
+	}
+	m.deleteRotation(func(key rotationMemberKey) bool {
+		return key.bannerID == bannerID && key.slotID == slotID
+	})
+	return nil
+}
+
+func (m *memoryDatabase) setBannerStatus(bannerID, slotID int, status string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	banner, ok := m.banners[bannerID]
+	if !ok {
+		return ErrNotExist
+	}
+	if slotID == 0 {
+		if banner.Status == structures.BannerArchived {
+			return ErrBannerArchived
+		}
+		banner.Status = status
+		banner.UpdatedAt = m.options.currentTime()
+		m.banners[bannerID] = banner
+		return nil
+	}
+	if _, ok := m.slots[slotID]; !ok {
+		return ErrNotExist
+	}
+	key := rotationMemberKey{slotID: slotID, bannerID: bannerID}
+	member, ok := m.rotation[key]
+	if !ok {
+		return ErrNotInRotation
+	}
+	member.status = status
+	m.rotation[key] = member
+	return nil
+}
+
+func (m *memoryDatabase) DatabasePauseBanner(bannerID, slotID int) error {
+	return m.setBannerStatus(bannerID, slotID, rotationPaused)
+}
+
+func (m *memoryDatabase) DatabaseResumeBanner(bannerID, slotID int) error {
+	return m.setBannerStatus(bannerID, slotID, rotationActive)
+}
+
+func (m *memoryDatabase) DatabaseSetRotationFlight(bannerID, slotID int, flight structures.Flight) error {
+	if err := validateFlight(flight); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkRotationEntities(bannerID, slotID); err != nil {
+		return err
+	}
+	key := rotationMemberKey{slotID: slotID, bannerID: bannerID}
+	member, ok := m.rotation[key]
+	if !ok {
+		return ErrNotInRotation
+	}
+	member.flight = flight
+	m.rotation[key] = member
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseSetRotationPriority(bannerID, slotID int, priority structures.Priority) error {
+	if err := validatePriority(priority); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkRotationEntities(bannerID, slotID); err != nil {
+		return err
+	}
+	key := rotationMemberKey{slotID: slotID, bannerID: bannerID}
+	member, ok := m.rotation[key]
+	if !ok {
+		return ErrNotInRotation
+	}
+	if err := checkShares(m.slotShares(key), priority); err != nil {
+		return err
+	}
+	member.weight, member.share = priority.Weight, priority.Share
+	m.rotation[key] = member
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseSelectBanners(slotID, groupID, count int, excluded []int,
+) (structures.SelectedBanners, error) {
+	var selected structures.SelectedBanners
+	if count < 1 {
+		return selected, ErrIncorrectCount
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.groups[groupID]; !ok {
+		return selected, ErrNotExist
+	}
+	slot, ok := m.slots[slotID]
+	if !ok {
+		return selected, ErrNotExist
+	}
+
+	banners := make([]int, 0)
+	current := m.options.currentTime()
+	for key, member := range m.rotation {
+		if key.slotID == slotID && member.status == rotationActive &&
+			m.banners[key.bannerID].Status == structures.BannerActive && flightActive(member.flight, current) {
+			banners = append(banners, key.bannerID)
+		}
+	}
+	if len(banners) == 0 {
+		return selected, ErrNotInRotation
+	}
+	sort.Ints(banners)
+
+	// Отсутствующая статистика группы равна нулю и создается при показе
+	stats := make([]bannerselector.ArmStats, len(banners))
+	for i, id := range banners {
+		stats[i] = m.statistic[statisticKey{slotID: slotID, groupID: groupID, bannerID: id}]
+		member := m.rotation[rotationMemberKey{slotID: slotID, bannerID: id}]
+		stats[i].Weight, stats[i].Share = member.weight, member.share
+	}
+	strategy, err := newSlotStrategy(slot.Strategy, m.options.random)
+	if err != nil {
+		return selected, err
+	}
+	if hours := historyHours(strategy); hours > 0 {
+		prepareHistory(stats)
+		for age, bucket := range historyBuckets(m.options.currentTime(), hours) {
+			for i, id := range banners {
+				key := historyKey{statisticKey: statisticKey{slotID: slotID, groupID: groupID, bannerID: id}, bucket: bucket}
+				arm := m.history[key]
+				addBucket(&stats[i], age, arm.Displays, arm.Clicks)
+			}
+		}
+	}
+	indexes, err := selectDistinct(strategy, banners, stats, excluded, count)
+	if err != nil {
+		return selected, err
+	}
+
+	rows := make([]structures.Banner, len(banners))
+	for i, id := range banners {
+		rows[i] = m.banners[id]
+	}
+	for _, index := range indexes {
+		m.addStatistic(statisticKey{slotID: slotID, groupID: groupID, bannerID: banners[index]}, 1, 0)
+	}
+	return selectedBanners(rows, indexes, slot.Strategy.Algorithm), nil
+}
+
+func (m *memoryDatabase) useImpression(impressionID string, expiresAt time.Time) error {
+	current := m.options.currentTime()
+	if current.Sub(m.impressionsSwept) >= impressionSweepInterval {
+		for id, expires := range m.impressions {
+			if !expires.After(current) {
+				delete(m.impressions, id)
+			}
+		}
+		m.impressionsSwept = current
+	}
+	// Истекший показ не принимается проверкой токена, даже если его запись еще не удалена
+	if expires, ok := m.impressions[impressionID]; ok && expires.After(current) {
+		return ErrImpressionUsed
+	}
+	m.impressions[impressionID] = expiresAt
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseRegisterTransition(slotID, bannerID, groupID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkTransition(slotID, bannerID, groupID); err != nil {
+		return err
+	}
+
+	m.addStatistic(statisticKey{slotID: slotID, groupID: groupID, bannerID: bannerID}, 0, 1)
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseRegisterImpressionTransition(impressionID string, expiresAt time.Time,
+	slotID, bannerID, groupID int,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.checkTransition(slotID, bannerID, groupID); err != nil {
+		return err
+	}
+	if err := m.useImpression(impressionID, expiresAt); err != nil {
+		return err
+	}
+
+	m.addStatistic(statisticKey{slotID: slotID, groupID: groupID, bannerID: bannerID}, 0, 1)
+	return nil
+}
+
+func (m *memoryDatabase) checkTransition(slotID, bannerID, groupID int) error {
+	if err := m.checkRotationEntities(bannerID, slotID); err != nil {
+		return err
+	}
+	if _, ok := m.groups[groupID]; !ok {
+		return ErrNotExist
+	}
+	if _, ok := m.rotation[rotationMemberKey{slotID: slotID, bannerID: bannerID}]; !ok {
+		return ErrNotInRotation
+	}
+	return nil
+}
+
+func (m *memoryDatabase) DatabaseGetStatistics(filter structures.StatisticFilter) ([]structures.Statistic, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := make([]structures.Statistic, 0)
+	exploration := make(map[int]*float64)
+	for key := range m.rotation {
+		if filter.SlotID != 0 && key.slotID != filter.SlotID {
+			continue
+		}
+		exploration[key.slotID] = m.slots[key.slotID].Strategy.Exploration
+		for groupID := range m.groups {
+			if filter.GroupID != 0 && groupID != filter.GroupID {
+				continue
+			}
+			arm := m.statistic[statisticKey{slotID: key.slotID, groupID: groupID, bannerID: key.bannerID}]
+			items = append(items, structures.Statistic{
+				SlotID:   key.slotID,
+				GroupID:  groupID,
+				BannerID: key.bannerID,
+				Displays: arm.Displays,
+				Clicks:   arm.Clicks,
+			})
+		}
+	}
+	return scoreStatistics(items, exploration, filter.BannerID), nil
+}
+
+func (m *memoryDatabase) DatabaseGetStatisticsHistory(filter structures.HistoryFilter) (
+	[]structures.HistoryPoint, error,
+) {
+	if err := validateHistoryFilter(&filter); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	points := make([]structures.HistoryPoint, 0)
+	for key, arm := range m.history {
+		if !matchHistory(filter, key.statisticKey, key.bucket) {
+			continue
+		}
+		points = append(points, structures.HistoryPoint{
+			SlotID:   key.slotID,
+			GroupID:  key.groupID,
+			BannerID: key.bannerID,
+			Time:     time.Unix(key.bucket, 0),
+			Displays: arm.Displays,
+			Clicks:   arm.Clicks,
+		})
+	}
+	return aggregateHistory(points, filter.Granularity), nil
+}
+
+func (m *memoryDatabase) DatabaseGetRotationMembers(filter structures.RotationFilter) (
+	[]structures.RotationMember, error,
+) {
+	m.mu.Lock()
+	members := make([]structures.RotationMember, 0)
+	for key, member := range m.rotation {
+		if (filter.SlotID != 0 && key.slotID != filter.SlotID) ||
+			(filter.BannerID != 0 && key.bannerID != filter.BannerID) {
+			continue
+		}
+		members = append(members, structures.RotationMember{
+			SlotID:   key.slotID,
+			Banner:   m.banners[key.bannerID],
+			Status:   member.status,
+			AddedAt:  member.addedAt,
+			Flight:   member.flight,
+			Priority: structures.Priority{Weight: member.weight, Share: member.share},
+		})
+	}
+	m.mu.Unlock()
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].SlotID != members[j].SlotID {
+			return members[i].SlotID < members[j].SlotID
+		}
+		return members[i].Banner.ID < members[j].Banner.ID
+	})
+
+	statistics, err := m.DatabaseGetStatistics(structures.StatisticFilter{
+		SlotID:   filter.SlotID,
+		BannerID: filter.BannerID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	attachStatistics(members, statistics)
+	return members, nil
+}
@@ -0,0 +1,584 @@
+package database
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/stretchr/testify/require"
+)
+
+func setMemoryTestData(t *testing.T, opts ...Option) Database {
+	t.Helper()
+	m := NewMemoryDatabase(opts...)
+	for i := 0; i < 10; i++ {
+		_, _ = m.DatabaseCreateBanner(structures.Banner{Info: "banner_" + strconv.Itoa(i+1)})
+	}
+	for i := 0; i < 2; i++ {
+		_, _ = m.DatabaseCreateGroup(structures.Group{Info: "group_" + strconv.Itoa(i+1)})
+	}
+	for i := 0; i < 4; i++ {
+		_, _ = m.DatabaseCreateSlot(structures.Slot{Info: "slot_" + strconv.Itoa(i+1)})
+	}
+	return m
+}
+
+func TestMemoryEntities(t *testing.T) {
+	m := NewMemoryDatabase()
+	closeConnection, err := m.DatabaseConnect(nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = closeConnection()
+	}()
+
+	t.Run("create and get", func(t *testing.T) {
+		banner, err := m.DatabaseCreateBanner(structures.Banner{ID: 10, Info: "info"})
+		require.NoError(t, err)
+		require.Equal(t, banner.ID, 1)
+		fromDB, err := m.DatabaseGetBanner(banner.ID)
+		require.NoError(t, err)
+		require.Equal(t, fromDB, banner)
+
+		slot, err := m.DatabaseCreateSlot(structures.Slot{Info: "info"})
+		require.NoError(t, err)
+		require.Equal(t, slot.Strategy.Algorithm, bannerselector.DefaultAlgorithm)
+
+		group, err := m.DatabaseCreateGroup(structures.Group{Info: "info"})
+		require.NoError(t, err)
+		require.Equal(t, group.ID, 1)
+	})
+
+	t.Run("non existed", func(t *testing.T) {
+		banner, err := m.DatabaseGetBanner(100)
+		require.ErrorIs(t, err, ErrNotExist)
+		require.Equal(t, banner.ID, invalidID)
+		_, err = m.DatabaseGetSlot(100)
+		require.ErrorIs(t, err, ErrNotExist)
+		_, err = m.DatabaseGetGroup(100)
+		require.ErrorIs(t, err, ErrNotExist)
+
+		require.ErrorIs(t, m.DatabaseUpdateBanner(structures.Banner{ID: 100}), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseUpdateSlot(structures.Slot{ID: 100}), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseUpdateGroup(structures.Group{ID: 100}), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseDeleteBanner(100), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseDeleteSlot(100), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseDeleteGroup(100), ErrNotExist)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		banner, _ := m.DatabaseCreateBanner(structures.Banner{Info: "info"})
+		banner.Info = newInfo
+		require.NoError(t, m.DatabaseUpdateBanner(banner))
+		updated, _ := m.DatabaseGetBanner(banner.ID)
+		require.Equal(t, updated.Info, newInfo)
+
+		require.NoError(t, m.DatabaseDeleteBanner(banner.ID))
+		_, err := m.DatabaseGetBanner(banner.ID)
+		require.ErrorIs(t, err, ErrNotExist)
+	})
+
+	t.Run("banner fields", func(t *testing.T) {
+		current := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+		m := NewMemoryDatabase(WithClock(func() time.Time { return current }))
+		banner, err := m.DatabaseCreateBanner(structures.Banner{
+			Info: "info", TargetURL: "https://example.com/sale", CreativeURL: "https://cdn.example.com/1.png",
+			Width: 300, Height: 250,
+		})
+		require.NoError(t, err)
+		require.Equal(t, structures.BannerActive, banner.Status)
+		require.Equal(t, current, banner.CreatedAt)
+
+		current = current.Add(time.Hour)
+		banner.Status = structures.BannerPaused
+		require.NoError(t, m.DatabaseUpdateBanner(banner))
+		updated, _ := m.DatabaseGetBanner(banner.ID)
+		require.Equal(t, structures.BannerPaused, updated.Status)
+		require.Equal(t, banner.CreatedAt, updated.CreatedAt)
+		require.Equal(t, current, updated.UpdatedAt)
+
+		for _, incorrect := range []structures.Banner{
+			{Status: "deleted"},
+			{Width: -1},
+			{TargetURL: "example.com"},
+			{CreativeURL: "ftp://example.com/1.png"},
+		} {
+			_, err := m.DatabaseCreateBanner(incorrect)
+			require.ErrorIs(t, err, ErrIncorrectEntity)
+		}
+		require.ErrorIs(t, m.DatabaseUpdateBanner(structures.Banner{ID: banner.ID, Height: -1}), ErrIncorrectEntity)
+		_, err = m.DatabaseCreateSlot(structures.Slot{Width: -1})
+		require.ErrorIs(t, err, ErrIncorrectEntity)
+	})
+
+	t.Run("incorrect strategy", func(t *testing.T) {
+		_, err := m.DatabaseCreateSlot(structures.Slot{Strategy: structures.Strategy{Algorithm: "unknown"}})
+		require.ErrorIs(t, err, ErrIncorrectStrategy)
+	})
+
+	t.Run("history strategy bounds", func(t *testing.T) {
+		for _, strategy := range []structures.Strategy{
+			{Algorithm: bannerselector.SlidingWindowUCB, Window: ptr(maxHistoryHours + 1)},
+			{Algorithm: bannerselector.DiscountedUCB, Discount: ptr(1.0)},
+			{Algorithm: bannerselector.DiscountedUCB, Discount: ptr(0.0)},
+			{Algorithm: bannerselector.DiscountedUCB, Discount: ptr(0.9999)},
+		} {
+			_, err := m.DatabaseCreateSlot(structures.Slot{Strategy: strategy})
+			require.ErrorIs(t, err, ErrIncorrectEntity)
+		}
+
+		slot, err := m.DatabaseCreateSlot(structures.Slot{
+			Strategy: structures.Strategy{Algorithm: bannerselector.SlidingWindowUCB, Window: ptr(maxHistoryHours)},
+		})
+		require.NoError(t, err)
+		slot.Strategy.Window = ptr(maxHistoryHours + 1)
+		require.ErrorIs(t, m.DatabaseUpdateSlot(slot), ErrIncorrectEntity)
+	})
+
+	t.Run("explicit zero strategy param", func(t *testing.T) {
+		strategy := structures.Strategy{Algorithm: bannerselector.EpsilonGreedy, Epsilon: ptr(0.0)}
+		slot, err := m.DatabaseCreateSlot(structures.Slot{Info: "greedy", Strategy: strategy})
+		require.NoError(t, err)
+		slot, _ = m.DatabaseGetSlot(slot.ID)
+		require.Equal(t, strategy, slot.Strategy)
+	})
+}
+
+func TestMemoryList(t *testing.T) {
+	m := setMemoryTestData(t)
+
+	banners, err := m.DatabaseGetBanners(structures.ListParams{})
+	require.NoError(t, err)
+	require.Len(t, banners, 10)
+	require.Equal(t, 1, banners[0].ID)
+
+	banners, err = m.DatabaseGetBanners(structures.ListParams{
+		Limit: 3, Offset: 1, Sort: structures.SortByInfo, Desc: true, Info: "BANNER_",
+	})
+	require.NoError(t, err)
+	require.Len(t, banners, 3)
+	for i, id := range []int{8, 7, 6} {
+		require.Equal(t, id, banners[i].ID)
+		require.Equal(t, "banner_"+strconv.Itoa(id), banners[i].Info)
+	}
+
+	slots, err := m.DatabaseGetSlots(structures.ListParams{Info: "slot_3"})
+	require.NoError(t, err)
+	require.Len(t, slots, 1)
+	require.Equal(t, 3, slots[0].ID)
+
+	groups, err := m.DatabaseGetGroups(structures.ListParams{Offset: 5})
+	require.NoError(t, err)
+	require.Empty(t, groups)
+
+	_, err = m.DatabaseGetGroups(structures.ListParams{Sort: "unknown"})
+	require.ErrorIs(t, err, ErrIncorrectListParams)
+	_, err = m.DatabaseGetGroups(structures.ListParams{Limit: -1})
+	require.ErrorIs(t, err, ErrIncorrectListParams)
+}
+
+func TestMemoryRotation(t *testing.T) {
+	t.Run("add and delete", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		require.NoError(t, m.DatabaseAddToRotation(1, 1))
+		require.ErrorIs(t, m.DatabaseAddToRotation(1, 1), ErrAlreadyInRotation)
+		require.ErrorIs(t, m.DatabaseAddToRotation(20, 1), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseAddToRotation(1, 20), ErrNotExist)
+
+		require.ErrorIs(t, m.DatabaseDeleteFromRotation(2, 1), ErrNotInRotation)
+		require.NoError(t, m.DatabaseDeleteFromRotation(1, 1))
+		_, err := SelectFromRotation(m, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+	})
+
+	t.Run("group created after rotation", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		require.NoError(t, m.DatabaseAddToRotation(1, 1))
+		group, err := m.DatabaseCreateGroup(structures.Group{})
+		require.NoError(t, err)
+		require.ErrorIs(t, m.DatabaseAddToRotation(1, 1), ErrAlreadyInRotation)
+		bannerID, err := SelectFromRotation(m, 1, group.ID)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 1)
+	})
+
+	t.Run("rotation without groups", func(t *testing.T) {
+		m := NewMemoryDatabase()
+		banner, _ := m.DatabaseCreateBanner(structures.Banner{})
+		slot, _ := m.DatabaseCreateSlot(structures.Slot{})
+		require.NoError(t, m.DatabaseAddToRotation(banner.ID, slot.ID))
+
+		group, _ := m.DatabaseCreateGroup(structures.Group{})
+		bannerID, err := SelectFromRotation(m, slot.ID, group.ID)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, banner.ID)
+		require.NoError(t, m.DatabaseRegisterTransition(slot.ID, banner.ID, group.ID))
+	})
+
+	t.Run("select and register", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		for groupID := 1; groupID <= 2; groupID++ {
+			bannerID, err := SelectFromRotation(m, 1, groupID)
+			require.NoError(t, err)
+			require.Equal(t, bannerID, 1)
+			bannerID, err = SelectFromRotation(m, 1, groupID)
+			require.NoError(t, err)
+			require.Equal(t, bannerID, 2)
+		}
+		require.NoError(t, m.DatabaseRegisterTransition(1, 2, 1))
+		require.ErrorIs(t, m.DatabaseRegisterTransition(1, 3, 1), ErrNotInRotation)
+		require.ErrorIs(t, m.DatabaseRegisterTransition(1, 2, 100), ErrNotExist)
+
+		_, err := SelectFromRotation(m, 1, 100)
+		require.ErrorIs(t, err, ErrNotExist)
+		_, err = SelectFromRotation(m, 100, 1)
+		require.ErrorIs(t, err, ErrNotExist)
+	})
+
+	t.Run("seeded random", func(t *testing.T) {
+		// Одинаковое зерно дает одинаковую последовательность показов вероятностного алгоритма
+		selectAll := func(seed int64) []int {
+			m := NewMemoryDatabase(WithRandom(bannerselector.NewRandom(seed)))
+			slot, _ := m.DatabaseCreateSlot(structures.Slot{Strategy: structures.Strategy{
+				Algorithm: bannerselector.EpsilonGreedy,
+				Epsilon:   ptr(1.0),
+			}})
+			group, _ := m.DatabaseCreateGroup(structures.Group{})
+			for i := 0; i < 5; i++ {
+				banner, _ := m.DatabaseCreateBanner(structures.Banner{})
+				require.NoError(t, m.DatabaseAddToRotation(banner.ID, slot.ID))
+			}
+			bannerIDs := make([]int, 0, 20)
+			for i := 0; i < 20; i++ {
+				bannerID, err := SelectFromRotation(m, slot.ID, group.ID)
+				require.NoError(t, err)
+				bannerIDs = append(bannerIDs, bannerID)
+			}
+			return bannerIDs
+		}
+		require.Equal(t, selectAll(42), selectAll(42))
+		require.NotEqual(t, selectAll(42), selectAll(43))
+	})
+
+	t.Run("members", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		_ = m.DatabaseAddToRotation(1, 2)
+		_, _ = SelectFromRotation(m, 1, 2)
+		require.NoError(t, m.DatabaseRegisterTransition(1, 1, 2))
+
+		members, err := m.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+		require.NoError(t, err)
+		require.Len(t, members, 2)
+		require.Equal(t, 1, members[0].Banner.ID)
+		require.Equal(t, "banner_1", members[0].Banner.Info)
+		require.Equal(t, rotationActive, members[0].Status)
+		require.False(t, members[0].AddedAt.IsZero())
+		require.Len(t, members[0].Statistics, 2)
+		require.Equal(t, 1, members[0].Statistics[1].Displays)
+		require.Equal(t, 1, members[0].Statistics[1].Clicks)
+
+		members, err = m.DatabaseGetRotationMembers(structures.RotationFilter{BannerID: 1})
+		require.NoError(t, err)
+		require.Len(t, members, 2)
+		require.Equal(t, 2, members[1].SlotID)
+		for _, item := range members[1].Statistics {
+			require.Equal(t, 2, item.SlotID)
+			require.Equal(t, 1, item.BannerID)
+		}
+
+		members, err = m.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 3})
+		require.NoError(t, err)
+		require.Empty(t, members)
+	})
+
+	t.Run("pause and resume", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		_ = m.DatabaseAddToRotation(1, 2)
+
+		require.NoError(t, m.DatabasePauseBanner(1, 1))
+		for i := 0; i < 3; i++ {
+			bannerID, err := SelectFromRotation(m, 1, 1)
+			require.NoError(t, err)
+			require.Equal(t, 2, bannerID)
+		}
+		// в другом слоте баннер продолжает показываться
+		bannerID, err := SelectFromRotation(m, 2, 1)
+		require.NoError(t, err)
+		require.Equal(t, 1, bannerID)
+		require.NoError(t, m.DatabaseRegisterTransition(1, 1, 1))
+
+		require.NoError(t, m.DatabasePauseBanner(2, 0))
+		_, err = SelectFromRotation(m, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+
+		require.NoError(t, m.DatabaseResumeBanner(1, 1))
+		require.NoError(t, m.DatabaseResumeBanner(2, 0))
+		statistics, err := m.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 2})
+		require.NoError(t, err)
+		require.Len(t, statistics, 1)
+		require.Equal(t, 3, statistics[0].Displays)
+		members, err := m.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+		require.NoError(t, err)
+		require.Equal(t, rotationActive, members[0].Status)
+
+		archived, _ := m.DatabaseCreateBanner(structures.Banner{Status: structures.BannerArchived})
+		require.ErrorIs(t, m.DatabasePauseBanner(archived.ID, 0), ErrBannerArchived)
+		require.ErrorIs(t, m.DatabaseResumeBanner(archived.ID, 0), ErrBannerArchived)
+		archived, _ = m.DatabaseGetBanner(archived.ID)
+		require.Equal(t, structures.BannerArchived, archived.Status)
+
+		require.ErrorIs(t, m.DatabasePauseBanner(1, 3), ErrNotInRotation)
+		require.ErrorIs(t, m.DatabasePauseBanner(100, 0), ErrNotExist)
+		require.ErrorIs(t, m.DatabaseResumeBanner(1, 100), ErrNotExist)
+	})
+
+	t.Run("flight", func(t *testing.T) {
+		current := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+		m := setMemoryTestData(t, WithClock(func() time.Time { return current }))
+		startAt, endAt := current.Add(time.Hour), current.Add(2*time.Hour)
+		_ = m.DatabaseAddToRotation(1, 1)
+		require.NoError(t, m.DatabaseSetRotationFlight(1, 1, structures.Flight{StartAt: &startAt, EndAt: &endAt}))
+
+		_, err := SelectFromRotation(m, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+		current = startAt
+		bannerID, err := SelectFromRotation(m, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, 1, bannerID)
+		current = endAt
+		_, err = SelectFromRotation(m, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+
+		members, err := m.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+		require.NoError(t, err)
+		require.Equal(t, startAt, *members[0].StartAt)
+		require.Equal(t, endAt, *members[0].EndAt)
+
+		require.NoError(t, m.DatabaseSetRotationFlight(1, 1, structures.Flight{StartAt: &startAt}))
+		_, err = SelectFromRotation(m, 1, 1)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, m.DatabaseSetRotationFlight(1, 1, structures.Flight{StartAt: &endAt, EndAt: &startAt}),
+			ErrIncorrectFlight)
+		require.ErrorIs(t, m.DatabaseSetRotationFlight(2, 1, structures.Flight{}), ErrNotInRotation)
+		require.ErrorIs(t, m.DatabaseSetRotationFlight(100, 1, structures.Flight{}), ErrNotExist)
+	})
+
+	t.Run("add with flight and priority", func(t *testing.T) {
+		current := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+		m := NewMemoryDatabase(WithClock(func() time.Time { return current }))
+		banner, _ := m.DatabaseCreateBanner(structures.Banner{})
+		other, _ := m.DatabaseCreateBanner(structures.Banner{})
+		slot, _ := m.DatabaseCreateSlot(structures.Slot{})
+		group, _ := m.DatabaseCreateGroup(structures.Group{})
+		startAt := current.Add(time.Hour)
+		flight := structures.Flight{StartAt: &startAt}
+		priority := structures.Priority{Weight: 2, Share: 0.6}
+
+		require.NoError(t, m.DatabaseAddToRotationWith(banner.ID, slot.ID, flight, priority))
+		members, err := m.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: slot.ID})
+		require.NoError(t, err)
+		require.Len(t, members, 1)
+		require.Equal(t, startAt, *members[0].StartAt)
+		require.Equal(t, priority, members[0].Priority)
+		require.Equal(t, current, members[0].AddedAt)
+		_, err = SelectFromRotation(m, slot.ID, group.ID)
+		require.ErrorIs(t, err, ErrNotInRotation)
+		current = startAt
+		bannerID, err := SelectFromRotation(m, slot.ID, group.ID)
+		require.NoError(t, err)
+		require.Equal(t, banner.ID, bannerID)
+
+		// некорректные параметры не добавляют баннер в ротацию
+		require.ErrorIs(t, m.DatabaseAddToRotationWith(other.ID, slot.ID, structures.Flight{},
+			structures.Priority{Weight: 1, Share: 0.5}), ErrIncorrectPriority)
+		require.ErrorIs(t, m.DatabaseAddToRotationWith(other.ID, slot.ID,
+			structures.Flight{StartAt: &startAt, EndAt: &startAt}, defaultPriority), ErrIncorrectFlight)
+		members, _ = m.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: slot.ID})
+		require.Len(t, members, 1)
+		require.ErrorIs(t, m.DatabaseAddToRotationWith(banner.ID, slot.ID, structures.Flight{}, defaultPriority),
+			ErrAlreadyInRotation)
+	})
+
+	t.Run("select except", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		for i := 0; i < 3; i++ {
+			bannerID, err := SelectFromRotationExcept(m, 1, 1, []int{1})
+			require.NoError(t, err)
+			require.Equal(t, 2, bannerID)
+		}
+		_, err := SelectFromRotationExcept(m, 1, 1, []int{1, 2})
+		require.ErrorIs(t, err, ErrNotInRotation)
+	})
+
+	t.Run("select many", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		_ = m.DatabaseAddToRotation(3, 1)
+
+		banners, err := SelectManyFromRotation(m, 1, 1, 2, nil)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 2}, banners)
+		// баннеров в ротации меньше, чем запрошено
+		banners, err = SelectManyFromRotation(m, 1, 1, 5, []int{1})
+		require.NoError(t, err)
+		require.ElementsMatch(t, []int{2, 3}, banners)
+
+		statistics, err := m.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1})
+		require.NoError(t, err)
+		displays := make(map[int]int)
+		for _, item := range statistics {
+			displays[item.BannerID] = item.Displays
+		}
+		require.Equal(t, map[int]int{1: 1, 2: 2, 3: 1}, displays)
+
+		_, err = SelectManyFromRotation(m, 1, 1, 0, nil)
+		require.ErrorIs(t, err, ErrIncorrectCount)
+		_, err = SelectManyFromRotation(m, 1, 1, 2, []int{1, 2, 3})
+		require.ErrorIs(t, err, ErrNotInRotation)
+	})
+
+	t.Run("select banners", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		banner, _ := m.DatabaseGetBanner(2)
+
+		selected, err := m.DatabaseSelectBanners(1, 1, 2, []int{1})
+		require.NoError(t, err)
+		require.Equal(t, []structures.Banner{banner}, selected.Banners)
+		require.Equal(t, bannerselector.DefaultAlgorithm, selected.Algorithm)
+	})
+
+	t.Run("priority", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		_ = m.DatabaseAddToRotation(2, 1)
+		_ = m.DatabaseAddToRotation(3, 1)
+		require.NoError(t, m.DatabaseSetRotationPriority(3, 1, structures.Priority{Weight: 2, Share: 0.5}))
+		for i := 0; i < 20; i++ {
+			_, err := SelectFromRotation(m, 1, 1)
+			require.NoError(t, err)
+		}
+		statistics, err := m.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 3})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, statistics[0].Displays, 10)
+
+		members, err := m.DatabaseGetRotationMembers(structures.RotationFilter{BannerID: 3})
+		require.NoError(t, err)
+		require.Equal(t, structures.Priority{Weight: 2, Share: 0.5}, members[0].Priority)
+
+		require.ErrorIs(t, m.DatabaseSetRotationPriority(1, 1, structures.Priority{Weight: 1, Share: 0.6}),
+			ErrIncorrectPriority)
+		require.NoError(t, m.DatabaseSetRotationPriority(3, 1, structures.Priority{Weight: 1, Share: 0.6}))
+		require.ErrorIs(t, m.DatabaseSetRotationPriority(1, 1, structures.Priority{}), ErrIncorrectPriority)
+		require.ErrorIs(t, m.DatabaseSetRotationPriority(4, 1, structures.Priority{Weight: 1}), ErrNotInRotation)
+	})
+
+	t.Run("delete entities removes statistic", func(t *testing.T) {
+		m := setMemoryTestData(t)
+		_ = m.DatabaseAddToRotation(1, 1)
+		require.NoError(t, m.DatabaseDeleteBanner(1))
+		_, err := SelectFromRotation(m, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+
+		_ = m.DatabaseAddToRotation(2, 2)
+		require.NoError(t, m.DatabaseDeleteGroup(1))
+		_, err = SelectFromRotation(m, 2, 2)
+		require.NoError(t, err)
+		require.NoError(t, m.DatabaseDeleteSlot(2))
+		_, err = SelectFromRotation(m, 2, 2)
+		require.ErrorIs(t, err, ErrNotExist)
+	})
+}
+
+func TestMemoryImpressions(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	m := setMemoryTestData(t, WithClock(func() time.Time { return current }))
+	_ = m.DatabaseAddToRotation(1, 1)
+	use := func(impressionID string, expiresAt time.Time) error {
+		return m.DatabaseRegisterImpressionTransition(impressionID, expiresAt, 1, 1, 1)
+	}
+
+	require.NoError(t, use("first", current.Add(time.Hour)))
+	require.ErrorIs(t, use("first", current.Add(time.Hour)), ErrImpressionUsed)
+	require.NoError(t, use("second", current.Add(time.Hour)))
+
+	current = current.Add(time.Hour)
+	require.NoError(t, use("third", current.Add(time.Hour)))
+	require.Len(t, m.(*memoryDatabase).impressions, 1)
+
+	// записи истекших показов удаляются не чаще раза в impressionSweepInterval
+	require.NoError(t, use("short", current.Add(time.Second)))
+	current = current.Add(2 * time.Second)
+	require.NoError(t, use("short", current.Add(time.Hour)))
+	require.NoError(t, use("fourth", current.Add(time.Hour)))
+	require.Len(t, m.(*memoryDatabase).impressions, 3)
+	current = current.Add(time.Hour + impressionSweepInterval)
+	require.NoError(t, use("fifth", current.Add(time.Hour)))
+	require.Len(t, m.(*memoryDatabase).impressions, 1)
+}
+
+func TestMemoryImpressionTransition(t *testing.T) {
+	m := setMemoryTestData(t)
+	_ = m.DatabaseAddToRotation(1, 1)
+	expiresAt := time.Now().Add(time.Hour)
+	clicks := func() int {
+		statistics, _ := m.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 1})
+		if len(statistics) == 0 {
+			return 0
+		}
+		return statistics[0].Clicks
+	}
+
+	// переход по баннеру вне ротации не расходует показ
+	require.ErrorIs(t, m.DatabaseRegisterImpressionTransition("first", expiresAt, 1, 2, 1), ErrNotInRotation)
+	require.NoError(t, m.DatabaseRegisterImpressionTransition("first", expiresAt, 1, 1, 1))
+	require.Equal(t, 1, clicks())
+	require.ErrorIs(t, m.DatabaseRegisterImpressionTransition("first", expiresAt, 1, 1, 1), ErrImpressionUsed)
+	require.Equal(t, 1, clicks())
+}
+
+func TestMemoryConcurrentSelect(t *testing.T) {
+	m := setMemoryTestData(t)
+	banners := []int{1, 2, 3}
+	for _, bannerID := range banners {
+		_ = m.DatabaseAddToRotation(bannerID, 1)
+	}
+
+	workers, perWorker := 10, 30
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	selected := make(map[int]int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				bannerID, err := SelectFromRotation(m, 1, 1)
+				if err != nil {
+					continue
+				}
+				mu.Lock()
+				selected[bannerID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, bannerID := range banners {
+		require.Equal(t, workers*perWorker/len(banners), selected[bannerID])
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "Statistic";
+DROP TABLE IF EXISTS "Groups";
+DROP TABLE IF EXISTS "Slots";
+DROP TABLE IF EXISTS "Banners";
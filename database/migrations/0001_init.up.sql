@@ -1,4 +1,4 @@
-CREATE SEQUENCE Banner_id_seq AS integer;
+CREATE SEQUENCE IF NOT EXISTS Banner_id_seq AS integer;
 CREATE TABLE IF NOT EXISTS "Banners"(
     "id" integer NOT NULL DEFAULT nextval('Banner_id_seq'),
     "info" text,
@@ -6,7 +6,7 @@ CREATE TABLE IF NOT EXISTS "Banners"(
 );
 ALTER SEQUENCE Banner_id_seq OWNED BY "Banners"."id";
 
-CREATE SEQUENCE Slot_id_seq AS integer;
+CREATE SEQUENCE IF NOT EXISTS Slot_id_seq AS integer;
 CREATE TABLE IF NOT EXISTS "Slots"(
     "id" integer NOT NULL DEFAULT nextval('Slot_id_seq'),
     "info" text,
@@ -14,7 +14,7 @@ CREATE TABLE IF NOT EXISTS "Slots"(
 );
 ALTER SEQUENCE Slot_id_seq OWNED BY "Slots"."id";
 
-CREATE SEQUENCE Group_id_seq AS integer;
+CREATE SEQUENCE IF NOT EXISTS Group_id_seq AS integer;
 CREATE TABLE IF NOT EXISTS "Groups"(
     "id" integer NOT NULL DEFAULT nextval('Group_id_seq'),
     "info" text,
@@ -31,4 +31,4 @@ CREATE TABLE IF NOT EXISTS "Statistic"(
     FOREIGN KEY ("slot_id") REFERENCES "Slots" ("id") ON DELETE CASCADE,
     FOREIGN KEY ("group_id") REFERENCES "Groups" ("id") ON DELETE CASCADE,
     FOREIGN KEY ("banner_id") REFERENCES "Banners" ("id") ON DELETE CASCADE 
-);
\ No newline at end of file
+);
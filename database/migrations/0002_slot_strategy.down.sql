@@ -0,0 +1,7 @@
+ALTER TABLE "Slots"
+    DROP COLUMN IF EXISTS "algorithm",
+    DROP COLUMN IF EXISTS "epsilon",
+    DROP COLUMN IF EXISTS "exploration",
+    DROP COLUMN IF EXISTS "temperature",
+    DROP COLUMN IF EXISTS "alpha",
+    DROP COLUMN IF EXISTS "beta";
@@ -0,0 +1,7 @@
+ALTER TABLE "Slots"
+    ADD COLUMN IF NOT EXISTS "algorithm" text NOT NULL DEFAULT 'ucb1',
+    ADD COLUMN IF NOT EXISTS "epsilon" double precision NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "exploration" double precision NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "temperature" double precision NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "alpha" double precision NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "beta" double precision NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE "Statistic" DROP CONSTRAINT IF EXISTS "Statistic_pkey";
@@ -0,0 +1,14 @@
+-- Дубликаты, которые могли появиться без ограничения, объединяются со сложением счетчиков
+CREATE TEMPORARY TABLE "Statistic_merged" ON COMMIT DROP AS
+SELECT slot_id, group_id, banner_id,
+    sum(display_count)::integer AS display_count,
+    sum(click_count)::integer AS click_count
+FROM "Statistic"
+WHERE slot_id IS NOT NULL AND group_id IS NOT NULL AND banner_id IS NOT NULL
+GROUP BY slot_id, group_id, banner_id;
+
+DELETE FROM "Statistic";
+INSERT INTO "Statistic"(slot_id, group_id, banner_id, display_count, click_count)
+SELECT slot_id, group_id, banner_id, display_count, click_count FROM "Statistic_merged";
+
+ALTER TABLE "Statistic" ADD CONSTRAINT "Statistic_pkey" PRIMARY KEY ("slot_id", "group_id", "banner_id");
@@ -0,0 +1,8 @@
+ALTER TABLE "Statistic" DROP CONSTRAINT IF EXISTS "Statistic_rotation_fkey";
+
+-- Состав ротации снова определяется строками статистики для каждой группы
+INSERT INTO "Statistic"(slot_id, group_id, banner_id)
+SELECT r.slot_id, g.id, r.banner_id FROM "Rotation" r CROSS JOIN "Groups" g
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS "Rotation";
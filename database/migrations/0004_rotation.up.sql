@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS "Rotation"(
+    "slot_id" integer NOT NULL,
+    "banner_id" integer NOT NULL,
+    "weight" double precision NOT NULL DEFAULT 1,
+    "status" text NOT NULL DEFAULT 'active',
+    "added_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("slot_id", "banner_id"),
+    FOREIGN KEY ("slot_id") REFERENCES "Slots" ("id") ON DELETE CASCADE,
+    FOREIGN KEY ("banner_id") REFERENCES "Banners" ("id") ON DELETE CASCADE
+);
+
+INSERT INTO "Rotation"(slot_id, banner_id)
+SELECT DISTINCT slot_id, banner_id FROM "Statistic"
+ON CONFLICT DO NOTHING;
+
+-- Строки статистики создаются при первом показе в группе
+-- и удаляются вместе с баннером из ротации
+DELETE FROM "Statistic" WHERE display_count = 0 AND click_count = 0;
+ALTER TABLE "Statistic" ADD CONSTRAINT "Statistic_rotation_fkey"
+    FOREIGN KEY ("slot_id", "banner_id") REFERENCES "Rotation" ("slot_id", "banner_id") ON DELETE CASCADE;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "StatisticHistory"(
+    "slot_id" integer NOT NULL,
+    "group_id" integer NOT NULL,
+    "banner_id" integer NOT NULL,
+    "bucket" timestamptz NOT NULL,
+    "display_count" integer NOT NULL DEFAULT 0,
+    "click_count" integer NOT NULL DEFAULT 0,
+    PRIMARY KEY ("slot_id", "group_id", "banner_id", "bucket"),
+    FOREIGN KEY ("slot_id", "banner_id") REFERENCES "Rotation" ("slot_id", "banner_id") ON DELETE CASCADE,
+    FOREIGN KEY ("group_id") REFERENCES "Groups" ("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "StatisticHistory_bucket_idx" ON "StatisticHistory" ("bucket");
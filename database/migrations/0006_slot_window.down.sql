@@ -0,0 +1,3 @@
+ALTER TABLE "Slots"
+    DROP COLUMN IF EXISTS "discount",
+    DROP COLUMN IF EXISTS "window";
@@ -0,0 +1,3 @@
+ALTER TABLE "Slots"
+    ADD COLUMN IF NOT EXISTS "discount" double precision NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS "window" integer NOT NULL DEFAULT 0;
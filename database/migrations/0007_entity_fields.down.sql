@@ -0,0 +1,16 @@
+ALTER TABLE "Groups"
+    DROP COLUMN IF EXISTS "description";
+
+ALTER TABLE "Slots"
+    DROP COLUMN IF EXISTS "width",
+    DROP COLUMN IF EXISTS "height",
+    DROP COLUMN IF EXISTS "placement";
+
+ALTER TABLE "Banners"
+    DROP COLUMN IF EXISTS "target_url",
+    DROP COLUMN IF EXISTS "creative_url",
+    DROP COLUMN IF EXISTS "width",
+    DROP COLUMN IF EXISTS "height",
+    DROP COLUMN IF EXISTS "status",
+    DROP COLUMN IF EXISTS "created_at",
+    DROP COLUMN IF EXISTS "updated_at";
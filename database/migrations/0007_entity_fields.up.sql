@@ -0,0 +1,17 @@
+ALTER TABLE "Banners"
+    ADD COLUMN IF NOT EXISTS "target_url" text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "creative_url" text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS "width" integer NOT NULL DEFAULT 0 CHECK ("width" >= 0),
+    ADD COLUMN IF NOT EXISTS "height" integer NOT NULL DEFAULT 0 CHECK ("height" >= 0),
+    ADD COLUMN IF NOT EXISTS "status" text NOT NULL DEFAULT 'active'
+        CHECK ("status" IN ('active', 'paused', 'archived')),
+    ADD COLUMN IF NOT EXISTS "created_at" timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS "updated_at" timestamptz NOT NULL DEFAULT now();
+
+ALTER TABLE "Slots"
+    ADD COLUMN IF NOT EXISTS "width" integer NOT NULL DEFAULT 0 CHECK ("width" >= 0),
+    ADD COLUMN IF NOT EXISTS "height" integer NOT NULL DEFAULT 0 CHECK ("height" >= 0),
+    ADD COLUMN IF NOT EXISTS "placement" text NOT NULL DEFAULT '';
+
+ALTER TABLE "Groups"
+    ADD COLUMN IF NOT EXISTS "description" text NOT NULL DEFAULT '';
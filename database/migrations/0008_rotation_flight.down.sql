@@ -0,0 +1,4 @@
+ALTER TABLE "Rotation"
+    DROP CONSTRAINT IF EXISTS "Rotation_flight_check",
+    DROP COLUMN IF EXISTS "end_at",
+    DROP COLUMN IF EXISTS "start_at";
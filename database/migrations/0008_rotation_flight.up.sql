@@ -0,0 +1,4 @@
+ALTER TABLE "Rotation"
+    ADD COLUMN IF NOT EXISTS "start_at" timestamptz,
+    ADD COLUMN IF NOT EXISTS "end_at" timestamptz,
+    ADD CONSTRAINT "Rotation_flight_check" CHECK ("start_at" IS NULL OR "end_at" IS NULL OR "end_at" > "start_at");
@@ -0,0 +1,3 @@
+ALTER TABLE "Rotation"
+    DROP CONSTRAINT IF EXISTS "Rotation_weight_check",
+    DROP COLUMN IF EXISTS "share";
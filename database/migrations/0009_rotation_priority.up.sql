@@ -0,0 +1,3 @@
+ALTER TABLE "Rotation"
+    ADD COLUMN IF NOT EXISTS "share" double precision NOT NULL DEFAULT 0 CHECK ("share" >= 0 AND "share" <= 1),
+    ADD CONSTRAINT "Rotation_weight_check" CHECK ("weight" > 0);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS "Impressions"(
+    "id" text PRIMARY KEY,
+    "expires_at" timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS "Impressions_expires_at_idx" ON "Impressions" ("expires_at");
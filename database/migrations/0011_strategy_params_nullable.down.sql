@@ -0,0 +1,23 @@
+UPDATE "Slots" SET
+    "epsilon" = coalesce("epsilon", 0),
+    "exploration" = coalesce("exploration", 0),
+    "temperature" = coalesce("temperature", 0),
+    "alpha" = coalesce("alpha", 0),
+    "beta" = coalesce("beta", 0),
+    "discount" = coalesce("discount", 0),
+    "window" = coalesce("window", 0);
+ALTER TABLE "Slots"
+    ALTER COLUMN "epsilon" SET DEFAULT 0,
+    ALTER COLUMN "epsilon" SET NOT NULL,
+    ALTER COLUMN "exploration" SET DEFAULT 0,
+    ALTER COLUMN "exploration" SET NOT NULL,
+    ALTER COLUMN "temperature" SET DEFAULT 0,
+    ALTER COLUMN "temperature" SET NOT NULL,
+    ALTER COLUMN "alpha" SET DEFAULT 0,
+    ALTER COLUMN "alpha" SET NOT NULL,
+    ALTER COLUMN "beta" SET DEFAULT 0,
+    ALTER COLUMN "beta" SET NOT NULL,
+    ALTER COLUMN "discount" SET DEFAULT 0,
+    ALTER COLUMN "discount" SET NOT NULL,
+    ALTER COLUMN "window" SET DEFAULT 0,
+    ALTER COLUMN "window" SET NOT NULL;
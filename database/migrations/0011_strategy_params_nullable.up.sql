@@ -0,0 +1,24 @@
+ALTER TABLE "Slots"
+    ALTER COLUMN "epsilon" DROP NOT NULL,
+    ALTER COLUMN "epsilon" DROP DEFAULT,
+    ALTER COLUMN "exploration" DROP NOT NULL,
+    ALTER COLUMN "exploration" DROP DEFAULT,
+    ALTER COLUMN "temperature" DROP NOT NULL,
+    ALTER COLUMN "temperature" DROP DEFAULT,
+    ALTER COLUMN "alpha" DROP NOT NULL,
+    ALTER COLUMN "alpha" DROP DEFAULT,
+    ALTER COLUMN "beta" DROP NOT NULL,
+    ALTER COLUMN "beta" DROP DEFAULT,
+    ALTER COLUMN "discount" DROP NOT NULL,
+    ALTER COLUMN "discount" DROP DEFAULT,
+    ALTER COLUMN "window" DROP NOT NULL,
+    ALTER COLUMN "window" DROP DEFAULT;
+-- Раньше нулевой параметр означал значение по умолчанию, теперь его обозначает NULL
+UPDATE "Slots" SET
+    "epsilon" = NULLIF("epsilon", 0),
+    "exploration" = NULLIF("exploration", 0),
+    "temperature" = NULLIF("temperature", 0),
+    "alpha" = NULLIF("alpha", 0),
+    "beta" = NULLIF("beta", 0),
+    "discount" = NULLIF("discount", 0),
+    "window" = NULLIF("window", 0);
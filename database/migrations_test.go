@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsLoad(t *testing.T) {
+	t.Run("embedded migrations", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationFiles)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+		for i, m := range migrations {
+			require.NotEmpty(t, m.up)
+			require.NotEmpty(t, m.down)
+			if i > 0 {
+				require.Greater(t, m.version, migrations[i-1].version)
+			}
+		}
+	})
+	t.Run("ordered by version", func(t *testing.T) {
+		files := fstest.MapFS{
+			"migrations/0010_second.up.sql":   {Data: []byte("up 10")},
+			"migrations/0010_second.down.sql": {Data: []byte("down 10")},
+			"migrations/0002_first.up.sql":    {Data: []byte("up 2")},
+			"migrations/0002_first.down.sql":  {Data: []byte("down 2")},
+		}
+		migrations, err := loadMigrations(files)
+		require.NoError(t, err)
+		require.Equal(t, []migration{
+			{version: 2, name: "first", up: "up 2", down: "down 2"},
+			{version: 10, name: "second", up: "up 10", down: "down 10"},
+		}, migrations)
+	})
+	t.Run("missing down", func(t *testing.T) {
+		files := fstest.MapFS{
+			"migrations/0001_init.up.sql": {Data: []byte("up")},
+		}
+		_, err := loadMigrations(files)
+		require.ErrorIs(t, err, ErrIncorrectMigrations)
+	})
+	t.Run("unexpected file", func(t *testing.T) {
+		files := fstest.MapFS{
+			"migrations/init.sql": {Data: []byte("up")},
+		}
+		_, err := loadMigrations(files)
+		require.ErrorIs(t, err, ErrIncorrectMigrations)
+	})
+}
+
+func migrationVersions(t *testing.T, db *sql.DB) []int {
+	t.Helper()
+	rows, err := db.Query(`SELECT version FROM "schema_migrations" ORDER BY version`)
+	require.NoError(t, err)
+	defer rows.Close()
+	versions := make([]int, 0)
+	for rows.Next() {
+		version := 0
+		require.NoError(t, rows.Scan(&version))
+		versions = append(versions, version)
+	}
+	require.NoError(t, rows.Err())
+	return versions
+}
+
+func TestMigrateDownUp(t *testing.T) {
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	var d databaseImpl
+	closeConnection, err := d.DatabaseConnect(config)
+	require.NoError(t, err)
+	defer func() {
+		_ = closeConnection()
+	}()
+
+	migrations, err := loadMigrations(migrationFiles)
+	require.NoError(t, err)
+	all := make([]int, 0, len(migrations))
+	for _, m := range migrations {
+		all = append(all, m.version)
+	}
+	require.Equal(t, all, migrationVersions(t, d.db))
+
+	require.NoError(t, Migrate(config, "down", 1))
+	require.Equal(t, all[:len(all)-1], migrationVersions(t, d.db))
+
+	require.NoError(t, Migrate(config, "up", 0))
+	require.Equal(t, all, migrationVersions(t, d.db))
+
+	// повторное применение ничего не меняет
+	require.NoError(t, migrateUp(d.db))
+	require.Equal(t, all, migrationVersions(t, d.db))
+
+	require.ErrorIs(t, Migrate(config, "sideways", 0), ErrIncorrectMigrations)
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+)
+
+// Option - необязательная настройка хранилища, передаваемая в конструктор.
+type Option func(*options)
+
+type options struct {
+	random bannerselector.Random
+	clock  func() time.Time
+}
+
+// WithRandom задает источник случайных чисел вероятностных алгоритмов слотов.
+// По умолчанию используется общий источник пакета math/rand.
+func WithRandom(random bannerselector.Random) Option {
+	return func(o *options) {
+		o.random = random
+	}
+}
+
+// WithClock задает источник текущего времени для периодов показов, почасовой статистики
+// и сроков действия показов. По умолчанию используется time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// currentTime возвращает текущее время часов хранилища.
+func (o options) currentTime() time.Time {
+	if o.clock == nil {
+		return time.Now()
+	}
+	return o.clock()
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisDatabase хранит сущности и состав ротации в Postgres,
+// а счетчики показов и переходов - в Redis.
+type redisDatabase struct {
+	*databaseImpl
+	address    string
+	statistics *redisStatistics
+}
+
+func NewRedisDatabase(address string, opts ...Option) Database {
+	return &redisDatabase{
+		databaseImpl: &databaseImpl{db: nil, options: newOptions(opts)},
+		address:      address,
+	}
+}
+
+func (r *redisDatabase) DatabaseConnect(config configs.DBConnectionConfig) (func() error, error) {
+	closeConnect, err := r.databaseImpl.DatabaseConnect(config)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     r.address,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		_ = closeConnect()
+		return nil, err
+	}
+	r.statistics = &redisStatistics{client: client, options: r.options}
+
+	return func() error {
+		if err := client.Close(); err != nil {
+			_ = closeConnect()
+			return err
+		}
+		return closeConnect()
+	}, nil
+}
+
+func (r *redisDatabase) DatabaseSelectBanners(slotID, groupID, count int, excluded []int,
+) (structures.SelectedBanners, error) {
+	var selected structures.SelectedBanners
+	if count < 1 {
+		return selected, ErrIncorrectCount
+	}
+	if err := checkEntityIsExists(r.databaseImpl, "Groups", groupID); err != nil {
+		return selected, err
+	}
+	slot, err := r.DatabaseGetSlot(slotID)
+	if err != nil {
+		return selected, err
+	}
+	banners, priorities, err := r.rotationBanners(slotID)
+	if err != nil {
+		return selected, err
+	}
+	if len(banners) == 0 {
+		return selected, ErrNotInRotation
+	}
+
+	strategy, err := newSlotStrategy(slot.Strategy, r.options.random)
+	if err != nil {
+		return selected, err
+	}
+	ids := bannerIDs(banners)
+	chosen, err := r.statistics.selectBanners(slotID, groupID, ids, priorities, excluded, count, strategy)
+	if err != nil {
+		return selected, err
+	}
+	// Данные баннеров загружены вместе с составом ротации до учета показов
+	indexes := make([]int, 0, len(chosen))
+	for _, bannerID := range chosen {
+		for i, id := range ids {
+			if id == bannerID {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return selectedBanners(banners, indexes, slot.Strategy.Algorithm), nil
+}
+
+func (r *redisDatabase) DatabaseRegisterTransition(slotID, bannerID, groupID int) error {
+	if err := r.checkTransition(slotID, bannerID, groupID); err != nil {
+		return err
+	}
+	return r.statistics.click(slotID, groupID, bannerID)
+}
+
+// DatabaseRegisterImpressionTransition фиксирует отметку показа только после учета перехода в Redis,
+// поэтому ошибка записи перехода не расходует показ.
+func (r *redisDatabase) DatabaseRegisterImpressionTransition(impressionID string, expiresAt time.Time,
+	slotID, bannerID, groupID int,
+) error {
+	if err := r.checkTransition(slotID, bannerID, groupID); err != nil {
+		return err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if err := useImpressionTx(tx, r.options.currentTime(), impressionID, expiresAt); err != nil {
+		return err
+	}
+	if err := r.statistics.click(slotID, groupID, bannerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *redisDatabase) checkTransition(slotID, bannerID, groupID int) error {
+	if err := checkEntityIsExists(r.databaseImpl, "Banners", bannerID); err != nil {
+		return err
+	}
+	if err := checkEntityIsExists(r.databaseImpl, "Slots", slotID); err != nil {
+		return err
+	}
+	if err := checkEntityIsExists(r.databaseImpl, "Groups", groupID); err != nil {
+		return err
+	}
+
+	// Переход засчитывается и приостановленному баннеру, показанному до паузы
+	count := 0
+	query := `SELECT count(*) FROM "Rotation" WHERE slot_id=$1 AND banner_id=$2`
+	if err := r.db.QueryRow(query, slotID, bannerID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotInRotation
+	}
+	return nil
+}
+
+func (r *redisDatabase) DatabaseDeleteFromRotation(bannerID, slotID int) error {
+	if err := r.databaseImpl.DatabaseDeleteFromRotation(bannerID, slotID); err != nil {
+		return err
+	}
+	return r.statistics.deleteFromRotation(bannerID, slotID)
+}
+
+func (r *redisDatabase) DatabaseDeleteBanner(id int) error {
+	if err := r.databaseImpl.DatabaseDeleteBanner(id); err != nil {
+		return err
+	}
+	return r.statistics.deleteBanner(id)
+}
+
+func (r *redisDatabase) DatabaseDeleteSlot(id int) error {
+	if err := r.databaseImpl.DatabaseDeleteSlot(id); err != nil {
+		return err
+	}
+	return r.statistics.deleteSlot(id)
+}
+
+func (r *redisDatabase) DatabaseDeleteGroup(id int) error {
+	if err := r.databaseImpl.DatabaseDeleteGroup(id); err != nil {
+		return err
+	}
+	return r.statistics.deleteGroup(id)
+}
+
+func (r *redisDatabase) DatabaseGetStatistics(filter structures.StatisticFilter) ([]structures.Statistic, error) {
+	items, exploration, err := r.statisticRows(filter)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.statistics.fill(items); err != nil {
+		return nil, err
+	}
+	return scoreStatistics(items, exploration, filter.BannerID), nil
+}
+
+func (r *redisDatabase) DatabaseGetStatisticsHistory(filter structures.HistoryFilter) (
+	[]structures.HistoryPoint, error,
+) {
+	if err := validateHistoryFilter(&filter); err != nil {
+		return nil, err
+	}
+	points, err := r.statistics.history(filter)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateHistory(points, filter.Granularity), nil
+}
+
+func (r *redisDatabase) DatabaseGetRotationMembers(filter structures.RotationFilter) (
+	[]structures.RotationMember, error,
+) {
+	return r.rotationMembers(filter, r.DatabaseGetStatistics)
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisDatabaseRotation(t *testing.T) {
+	server := miniredis.RunT(t)
+	r := NewRedisDatabase(server.Addr()).(*redisDatabase)
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, err := r.DatabaseConnect(config)
+	require.NoError(t, err)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(*r.databaseImpl)
+
+	t.Run("select and register", func(t *testing.T) {
+		_, _ = r.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		server.FlushAll()
+		_ = r.DatabaseAddToRotation(1, 1)
+		_ = r.DatabaseAddToRotation(2, 1)
+
+		bannerID, err := SelectFromRotation(r, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 1)
+		bannerID, err = SelectFromRotation(r, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 2)
+		require.NoError(t, r.DatabaseRegisterTransition(1, 2, 1))
+
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(1)), "1")
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), clicksField(2)), "1")
+
+		// Счетчики в Postgres не используются
+		displays, clicks := sumStatistic(r.databaseImpl, 1, 1)
+		require.Equal(t, displays, 0)
+		require.Equal(t, clicks, 0)
+	})
+
+	t.Run("select except and many", func(t *testing.T) {
+		_, _ = r.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		server.FlushAll()
+		_ = r.DatabaseAddToRotation(1, 1)
+		_ = r.DatabaseAddToRotation(2, 1)
+		_ = r.DatabaseAddToRotation(3, 1)
+
+		bannerID, err := SelectFromRotationExcept(r, 1, 1, []int{1})
+		require.NoError(t, err)
+		require.Equal(t, 2, bannerID)
+		_, err = SelectFromRotationExcept(r, 1, 1, []int{1, 2, 3})
+		require.ErrorIs(t, err, ErrNotInRotation)
+
+		banners, err := SelectManyFromRotation(r, 1, 1, 2, nil)
+		require.NoError(t, err)
+		require.Equal(t, []int{1, 3}, banners)
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(1)), "1")
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(2)), "1")
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(3)), "1")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _ = r.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		_, err := SelectFromRotation(r, 1, 100)
+		require.ErrorIs(t, err, ErrNotExist)
+		_, err = SelectFromRotation(r, 1, 1)
+		require.ErrorIs(t, err, ErrNotInRotation)
+		require.ErrorIs(t, r.DatabaseRegisterTransition(1, 1, 1), ErrNotInRotation)
+		require.ErrorIs(t, r.DatabaseRegisterTransition(1, 100, 1), ErrNotExist)
+	})
+
+	t.Run("delete from rotation", func(t *testing.T) {
+		_, _ = r.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		server.FlushAll()
+		_ = r.DatabaseAddToRotation(1, 1)
+		_, _ = SelectFromRotation(r, 1, 1)
+		require.NoError(t, r.DatabaseDeleteFromRotation(1, 1))
+		require.Empty(t, server.HGet(statisticRedisKey(1, 1), displaysField(1)))
+	})
+}
+
+func TestRedisDatabaseSlidingWindowSelect(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	server := miniredis.RunT(t)
+	r := NewRedisDatabase(server.Addr(), WithClock(func() time.Time { return current })).(*redisDatabase)
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, err := r.DatabaseConnect(config)
+	require.NoError(t, err)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(*r.databaseImpl)
+	_, _ = r.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	checkSlidingWindowSelect(t, r, &current)
+}
@@ -0,0 +1,326 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	maxSelectAttempts = 100
+	selectRetryDelay  = time.Millisecond
+)
+
+var errSelectConflict = errors.New("too many concurrent selections")
+
+// redisStatistics хранит счетчики показов и переходов в хэшах Redis:
+// ключ statistic:{slot_id}:{group_id}, поля {banner_id}:displays и {banner_id}:clicks.
+// Счетчики за час хранятся с теми же полями в ключах history:{slot_id}:{group_id}:{unix-время начала часа}.
+type redisStatistics struct {
+	client  *redis.Client
+	options options
+}
+
+func statisticRedisKey(slotID, groupID int) string {
+	return fmt.Sprintf("statistic:%d:%d", slotID, groupID)
+}
+
+func historyRedisKey(slotID, groupID int, bucket int64) string {
+	return fmt.Sprintf("history:%d:%d:%d", slotID, groupID, bucket)
+}
+
+func displaysField(bannerID int) string {
+	return strconv.Itoa(bannerID) + ":displays"
+}
+
+func clicksField(bannerID int) string {
+	return strconv.Itoa(bannerID) + ":clicks"
+}
+
+func redisCounter(value interface{}) (int, error) {
+	if value == nil {
+		return 0, nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected counter value %v", value)
+	}
+	return strconv.Atoi(str)
+}
+
+func bannerFields(banners []int) []string {
+	fields := make([]string, 0, 2*len(banners))
+	for _, bannerID := range banners {
+		fields = append(fields, displaysField(bannerID), clicksField(bannerID))
+	}
+	return fields
+}
+
+// counters разбирает ответ HMGET по полям bannerFields.
+func counters(values []interface{}, index int) (int, int, error) {
+	displays, err := redisCounter(values[2*index])
+	if err != nil {
+		return 0, 0, err
+	}
+	clicks, err := redisCounter(values[2*index+1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return displays, clicks, nil
+}
+
+func (r *redisStatistics) stats(ctx context.Context, cmd redis.Cmdable,
+	key string, banners []int,
+) ([]bannerselector.ArmStats, error) {
+	values, err := cmd.HMGet(ctx, key, bannerFields(banners)...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]bannerselector.ArmStats, len(banners))
+	for i := range banners {
+		if stats[i].Displays, stats[i].Clicks, err = counters(values, i); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// armHistory загружает почасовые счетчики баннеров за hours последних часов одним конвейером.
+func (r *redisStatistics) armHistory(ctx context.Context, cmd redis.Cmdable,
+	slotID, groupID int, banners []int, stats []bannerselector.ArmStats, hours int,
+) error {
+	if hours <= 0 {
+		return nil
+	}
+	prepareHistory(stats)
+	fields := bannerFields(banners)
+	buckets := historyBuckets(r.options.currentTime(), hours)
+	cmds := make([]*redis.SliceCmd, len(buckets))
+	if _, err := cmd.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for age, bucket := range buckets {
+			cmds[age] = pipe.HMGet(ctx, historyRedisKey(slotID, groupID, bucket), fields...)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for age, bucketCmd := range cmds {
+		values, err := bucketCmd.Result()
+		if err != nil {
+			return err
+		}
+		for i := range banners {
+			displays, clicks, err := counters(values, i)
+			if err != nil {
+				return err
+			}
+			addBucket(&stats[i], age, displays, clicks)
+		}
+	}
+	return nil
+}
+
+// selectBanners выбирает до count разных баннеров и увеличивает счетчики их показов.
+// Чтение и увеличение выполняются в оптимистичной транзакции WATCH/MULTI
+// и повторяются, если хэш изменился конкурентным запросом.
+func (r *redisStatistics) selectBanners(slotID, groupID int, banners []int,
+	priorities []structures.Priority, excluded []int, count int, strategy bannerselector.Strategy,
+) ([]int, error) {
+	ctx := context.Background()
+	key := statisticRedisKey(slotID, groupID)
+	var selected []int
+	txFunc := func(tx *redis.Tx) error {
+		stats, err := r.stats(ctx, tx, key, banners)
+		if err != nil {
+			return err
+		}
+		setPriorities(stats, priorities)
+		if err := r.armHistory(ctx, tx, slotID, groupID, banners, stats, historyHours(strategy)); err != nil {
+			return err
+		}
+		indexes, err := selectDistinct(strategy, banners, stats, excluded, count)
+		if err != nil {
+			return err
+		}
+		selected = make([]int, 0, len(indexes))
+		for _, index := range indexes {
+			selected = append(selected, banners[index])
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, bannerID := range selected {
+				pipe.HIncrBy(ctx, key, displaysField(bannerID), 1)
+				pipe.HIncrBy(ctx, historyRedisKey(slotID, groupID, hourBucket(r.options.currentTime())), displaysField(bannerID), 1)
+			}
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxSelectAttempts; i++ {
+		err := r.client.Watch(ctx, txFunc, key)
+		if err == nil {
+			return selected, nil
+		}
+		if !errors.Is(err, redis.TxFailedErr) {
+			return nil, err
+		}
+		// случайная пауза, чтобы конкурирующие запросы не повторялись синхронно
+		time.Sleep(time.Duration(rand.Int63n(int64(selectRetryDelay) * int64(i+1)))) //nolint:gosec
+	}
+	return nil, errSelectConflict
+}
+
+func (r *redisStatistics) click(slotID, groupID, bannerID int) error {
+	ctx := context.Background()
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, statisticRedisKey(slotID, groupID), clicksField(bannerID), 1)
+		pipe.HIncrBy(ctx, historyRedisKey(slotID, groupID, hourBucket(r.options.currentTime())), clicksField(bannerID), 1)
+		return nil
+	})
+	return err
+}
+
+// deleteFields удаляет счетчики баннера во всех ключах, подходящих под шаблон.
+func (r *redisStatistics) deleteFields(pattern string, bannerID int) error {
+	ctx := context.Background()
+	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.HDel(ctx, iter.Val(), displaysField(bannerID), clicksField(bannerID)).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (r *redisStatistics) deleteKeys(pattern string) error {
+	ctx := context.Background()
+	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (r *redisStatistics) deleteFromRotation(bannerID, slotID int) error {
+	if err := r.deleteFields(fmt.Sprintf("statistic:%d:*", slotID), bannerID); err != nil {
+		return err
+	}
+	return r.deleteFields(fmt.Sprintf("history:%d:*", slotID), bannerID)
+}
+
+func (r *redisStatistics) deleteBanner(bannerID int) error {
+	if err := r.deleteFields("statistic:*", bannerID); err != nil {
+		return err
+	}
+	return r.deleteFields("history:*", bannerID)
+}
+
+func (r *redisStatistics) deleteSlot(slotID int) error {
+	if err := r.deleteKeys(fmt.Sprintf("statistic:%d:*", slotID)); err != nil {
+		return err
+	}
+	return r.deleteKeys(fmt.Sprintf("history:%d:*", slotID))
+}
+
+func (r *redisStatistics) deleteGroup(groupID int) error {
+	if err := r.deleteKeys(fmt.Sprintf("statistic:*:%d", groupID)); err != nil {
+		return err
+	}
+	return r.deleteKeys(fmt.Sprintf("history:*:%d:*", groupID))
+}
+
+// fill заполняет счетчики строк статистики значениями из Redis.
+func (r *redisStatistics) fill(items []structures.Statistic) error {
+	ctx := context.Background()
+	byKey := make(map[string][]int)
+	for i, item := range items {
+		key := statisticRedisKey(item.SlotID, item.GroupID)
+		byKey[key] = append(byKey[key], i)
+	}
+	for key, indexes := range byKey {
+		banners := make([]int, 0, len(indexes))
+		for _, i := range indexes {
+			banners = append(banners, items[i].BannerID)
+		}
+		stats, err := r.stats(ctx, r.client, key, banners)
+		if err != nil {
+			return err
+		}
+		for j, i := range indexes {
+			items[i].Displays, items[i].Clicks = stats[j].Displays, stats[j].Clicks
+		}
+	}
+	return nil
+}
+
+// history возвращает часовые счетчики, подходящие под фильтр.
+func (r *redisStatistics) history(filter structures.HistoryFilter) ([]structures.HistoryPoint, error) {
+	ctx := context.Background()
+	points := make([]structures.HistoryPoint, 0)
+	slotPattern, groupPattern := "*", "*"
+	if filter.SlotID != 0 {
+		slotPattern = strconv.Itoa(filter.SlotID)
+	}
+	if filter.GroupID != 0 {
+		groupPattern = strconv.Itoa(filter.GroupID)
+	}
+	pattern := fmt.Sprintf("history:%s:%s:*", slotPattern, groupPattern)
+	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
+	for iter.Next(ctx) {
+		var key statisticKey
+		var bucket int64
+		if _, err := fmt.Sscanf(iter.Val(), "history:%d:%d:%d", &key.slotID, &key.groupID, &bucket); err != nil {
+			return nil, err
+		}
+		if !matchHistory(filter, key, bucket) {
+			continue
+		}
+		fields, err := r.client.HGetAll(ctx, iter.Val()).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		byBanner := make(map[int]*structures.HistoryPoint)
+		for field, value := range fields {
+			var bannerID int
+			var counter string
+			if _, err := fmt.Sscanf(strings.Replace(field, ":", " ", 1), "%d %s", &bannerID, &counter); err != nil {
+				return nil, err
+			}
+			if filter.BannerID != 0 && bannerID != filter.BannerID {
+				continue
+			}
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			point, ok := byBanner[bannerID]
+			if !ok {
+				point = &structures.HistoryPoint{
+					SlotID: key.slotID, GroupID: key.groupID, BannerID: bannerID, Time: time.Unix(bucket, 0),
+				}
+				byBanner[bannerID] = point
+			}
+			if counter == "displays" {
+				point.Displays = count
+			} else {
+				point.Clicks = count
+			}
+		}
+		for _, point := range byBanner {
+			points = append(points, *point)
+		}
+	}
+	return points, iter.Err()
+}
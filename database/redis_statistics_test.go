@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisStatistics(t *testing.T, opts ...Option) (*redisStatistics, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return &redisStatistics{client: client, options: newOptions(opts)}, server
+}
+
+func TestRedisSelectBanner(t *testing.T) {
+	r, server := newTestRedisStatistics(t)
+	strategy, _ := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+	banners := []int{3, 5}
+
+	t.Run("select and count displays", func(t *testing.T) {
+		selected, err := r.selectBanners(1, 1, banners, nil, nil, 1, strategy)
+		require.NoError(t, err)
+		require.Equal(t, []int{3}, selected)
+		selected, err = r.selectBanners(1, 1, banners, nil, nil, 1, strategy)
+		require.NoError(t, err)
+		require.Equal(t, []int{5}, selected)
+
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(3)), "1")
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(5)), "1")
+		require.Empty(t, server.HGet(statisticRedisKey(1, 1), clicksField(3)))
+	})
+
+	t.Run("clicks", func(t *testing.T) {
+		require.NoError(t, r.click(1, 1, 5))
+		require.NoError(t, r.click(1, 1, 5))
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), clicksField(5)), "2")
+		require.Empty(t, server.HGet(statisticRedisKey(1, 2), clicksField(5)))
+
+		stats, err := r.stats(context.Background(), r.client, statisticRedisKey(1, 1), banners)
+		require.NoError(t, err)
+		require.Equal(t, stats, []bannerselector.ArmStats{{Displays: 1, Clicks: 0}, {Displays: 1, Clicks: 2}})
+	})
+
+	t.Run("incorrect counters", func(t *testing.T) {
+		server.HSet(statisticRedisKey(2, 1), displaysField(3), "bad")
+		_, err := r.selectBanners(2, 1, banners, nil, nil, 1, strategy)
+		require.Error(t, err)
+	})
+}
+
+func TestRedisStatisticsPriority(t *testing.T) {
+	r, server := newTestRedisStatistics(t)
+	strategy, _ := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+	banners := []int{1, 2}
+	priorities := []structures.Priority{{Weight: 1}, {Weight: 1, Share: 0.5}}
+
+	for i := 0; i < 10; i++ {
+		_, err := r.selectBanners(1, 1, banners, priorities, nil, 1, strategy)
+		require.NoError(t, err)
+	}
+	require.Equal(t, "5", server.HGet(statisticRedisKey(1, 1), displaysField(2)))
+}
+
+func TestRedisStatisticsExcept(t *testing.T) {
+	r, _ := newTestRedisStatistics(t)
+	strategy, _ := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+	for i := 0; i < 3; i++ {
+		selected, err := r.selectBanners(1, 1, []int{1, 2}, nil, []int{1}, 1, strategy)
+		require.NoError(t, err)
+		require.Equal(t, []int{2}, selected)
+	}
+	_, err := r.selectBanners(1, 1, []int{1, 2}, nil, []int{1, 2}, 1, strategy)
+	require.ErrorIs(t, err, ErrNotInRotation)
+}
+
+func TestRedisStatisticsSelectMany(t *testing.T) {
+	r, server := newTestRedisStatistics(t)
+	strategy, _ := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+	banners, err := r.selectBanners(1, 1, []int{1, 2, 3}, nil, nil, 2, strategy)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, banners)
+	require.Equal(t, "1", server.HGet(statisticRedisKey(1, 1), displaysField(1)))
+	require.Equal(t, "1", server.HGet(statisticRedisKey(1, 1), displaysField(2)))
+	require.Empty(t, server.HGet(statisticRedisKey(1, 1), displaysField(3)))
+}
+
+func TestRedisConcurrentSelect(t *testing.T) {
+	r, server := newTestRedisStatistics(t)
+	strategy, _ := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+	banners := []int{1, 2, 3}
+
+	workers, perWorker := 10, 30
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				_, err := r.selectBanners(1, 1, banners, nil, nil, 1, strategy)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	for _, bannerID := range banners {
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(bannerID)), "100")
+	}
+}
+
+func TestRedisDeleteStatistics(t *testing.T) {
+	r, server := newTestRedisStatistics(t)
+	fill := func() {
+		server.FlushAll()
+		for _, key := range []string{statisticRedisKey(1, 1), statisticRedisKey(1, 2), statisticRedisKey(2, 1)} {
+			server.HSet(key, displaysField(1), "1")
+			server.HSet(key, displaysField(2), "1")
+		}
+	}
+
+	t.Run("delete from rotation", func(t *testing.T) {
+		fill()
+		require.NoError(t, r.deleteFromRotation(1, 1))
+		require.Empty(t, server.HGet(statisticRedisKey(1, 1), displaysField(1)))
+		require.Empty(t, server.HGet(statisticRedisKey(1, 2), displaysField(1)))
+		require.Equal(t, server.HGet(statisticRedisKey(2, 1), displaysField(1)), "1")
+		require.Equal(t, server.HGet(statisticRedisKey(1, 1), displaysField(2)), "1")
+	})
+
+	t.Run("delete banner", func(t *testing.T) {
+		fill()
+		require.NoError(t, r.deleteBanner(2))
+		require.Empty(t, server.HGet(statisticRedisKey(1, 1), displaysField(2)))
+		require.Empty(t, server.HGet(statisticRedisKey(2, 1), displaysField(2)))
+		require.Equal(t, server.HGet(statisticRedisKey(2, 1), displaysField(1)), "1")
+	})
+
+	t.Run("delete slot and group", func(t *testing.T) {
+		fill()
+		require.NoError(t, r.deleteSlot(1))
+		require.False(t, server.Exists(statisticRedisKey(1, 1)))
+		require.False(t, server.Exists(statisticRedisKey(1, 2)))
+		require.True(t, server.Exists(statisticRedisKey(2, 1)))
+
+		require.NoError(t, r.deleteGroup(1))
+		require.False(t, server.Exists(statisticRedisKey(2, 1)))
+	})
+}
+
+func TestRedisStatisticsFill(t *testing.T) {
+	r, server := newTestRedisStatistics(t)
+	server.HSet(statisticRedisKey(1, 1), displaysField(1), "3", clicksField(1), "1", displaysField(2), "2")
+	items := []structures.Statistic{
+		{SlotID: 1, GroupID: 1, BannerID: 1},
+		{SlotID: 1, GroupID: 1, BannerID: 2},
+		{SlotID: 1, GroupID: 2, BannerID: 1},
+	}
+
+	require.NoError(t, r.fill(items))
+	require.Equal(t, []structures.Statistic{
+		{SlotID: 1, GroupID: 1, BannerID: 1, Displays: 3, Clicks: 1},
+		{SlotID: 1, GroupID: 1, BannerID: 2, Displays: 2},
+		{SlotID: 1, GroupID: 2, BannerID: 1},
+	}, items)
+}
+
+func TestRedisStatisticsHistory(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	r, _ := newTestRedisStatistics(t, WithClock(func() time.Time { return current }))
+	strategy, _ := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+
+	_, err := r.selectBanners(1, 1, []int{1}, nil, nil, 1, strategy)
+	require.NoError(t, err)
+	require.NoError(t, r.click(1, 1, 1))
+	current = current.Add(time.Hour)
+	_, err = r.selectBanners(1, 2, []int{1}, nil, nil, 1, strategy)
+	require.NoError(t, err)
+
+	points, err := r.history(structures.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+
+	points, err = r.history(structures.HistoryFilter{StatisticFilter: structures.StatisticFilter{GroupID: 1}})
+	require.NoError(t, err)
+	require.Equal(t, []structures.HistoryPoint{{
+		SlotID: 1, GroupID: 1, BannerID: 1,
+		Time: time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC).Local(), Displays: 1, Clicks: 1,
+	}}, points)
+
+	require.NoError(t, r.deleteGroup(1))
+	points, _ = r.history(structures.HistoryFilter{})
+	require.Len(t, points, 1)
+}
+
+func TestRedisStatisticsSlidingWindow(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 30, 0, 0, time.UTC)
+	r, _ := newTestRedisStatistics(t, WithClock(func() time.Time { return current }))
+	strategy, _ := bannerselector.NewStrategy(bannerselector.SlidingWindowUCB, bannerselector.Params{Window: ptr(1)})
+	banners := []int{1, 2}
+
+	selectBanner := func(expected int) {
+		t.Helper()
+		selected, err := r.selectBanners(1, 1, banners, nil, nil, 1, strategy)
+		require.NoError(t, err)
+		require.Equal(t, []int{expected}, selected)
+	}
+	selectBanner(1)
+	require.NoError(t, r.click(1, 1, 1))
+	selectBanner(2)
+
+	current = current.Add(time.Hour)
+	selectBanner(1)
+	selectBanner(2)
+	require.NoError(t, r.click(1, 1, 2))
+	selectBanner(2)
+}
@@ -2,22 +2,147 @@ package database
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
 )
 
-func checkEntityInRotationTx(tx *sql.Tx, bannerID, slotID, groupID int) (bool, error) {
+// flightCondition отбирает строки ротации, период показов которых включает момент из параметра moment.
+func flightCondition(moment string) string {
+	return fmt.Sprintf(`(r.start_at IS NULL OR r.start_at <= %[1]s) AND (r.end_at IS NULL OR r.end_at > %[1]s)`, moment)
+}
+
+// Статусы баннера в ротации слота. Для показа выбираются баннеры,
+// активные и в ротации слота, и глобально (статус в "Banners").
+const (
+	rotationActive = "active"
+	rotationPaused = "paused"
+)
+
+func checkEntityInRotationTx(tx *sql.Tx, bannerID, slotID int) (bool, error) {
 	count := 0
-	if err := tx.QueryRow(`SELECT count(*) 
-	FROM "Statistic"
-	WHERE slot_id=$1 AND banner_id=$2 AND group_id=$3`,
-		slotID, bannerID, groupID).Scan(&count); err != nil {
+	if err := tx.QueryRow(`SELECT count(*)
+	FROM "Rotation"
+	WHERE slot_id=$1 AND banner_id=$2`,
+		slotID, bannerID).Scan(&count); err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func increaseDisplay(d *databaseImpl, bannerID, slotID, groupID int) error {
+func increaseDisplayTx(tx *sql.Tx, bannerID, slotID, groupID int) error {
+	query := `UPDATE "Statistic"
+	SET display_count = display_count + 1
+	WHERE slot_id=$1 AND group_id=$2 AND banner_id=$3`
+
+	_, err := tx.Exec(query, slotID, groupID, bannerID)
+	return err
+}
+
+// createStatisticTx создает недостающие строки статистики группы для баннеров в ротации слота.
+func createStatisticTx(tx *sql.Tx, slotID, groupID int) error {
+	query := `INSERT INTO "Statistic"(banner_id, slot_id, group_id, display_count, click_count)
+	SELECT banner_id, slot_id, $2, 0, 0 FROM "Rotation" WHERE slot_id=$1
+	ON CONFLICT (slot_id, group_id, banner_id) DO NOTHING`
+	_, err := tx.Exec(query, slotID, groupID)
+	return err
+}
+
+// selectRotationStatsTx загружает баннеры пары слот/группа, доступные для показа в момент t, и их статистику.
+func selectRotationStatsTx(tx *sql.Tx, t time.Time, slotID, groupID int,
+) ([]structures.Banner, []bannerselector.ArmStats, error) {
+	if err := createStatisticTx(tx, slotID, groupID); err != nil {
+		return nil, nil, err
+	}
+
+	// Строки блокируются до конца транзакции, поэтому конкурентные выборы
+	// для одной пары слот/группа выполняются последовательно и видят актуальные счетчики
+	query := `SELECT ` + bannerColumns + `, s.display_count, s.click_count, r.weight, r.share FROM "Statistic" s
+	JOIN "Rotation" r ON r.slot_id = s.slot_id AND r.banner_id = s.banner_id
+	JOIN "Banners" b ON b.id = s.banner_id
+	WHERE s.slot_id=$1 AND s.group_id=$2 AND r.status=$3 AND b.status=$4 AND ` + flightCondition("$5") + `
+	ORDER BY s.banner_id
+	FOR UPDATE OF s`
+	rows, err := tx.Query(query, slotID, groupID, rotationActive, structures.BannerActive, t)
+	if err != nil || rows.Err() != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	banners := make([]structures.Banner, 0)
+	stats := make([]bannerselector.ArmStats, 0)
+	for rows.Next() {
+		var banner structures.Banner
+		var arm bannerselector.ArmStats
+		if err := rows.Scan(&banner.ID, &banner.Info, &banner.TargetURL, &banner.CreativeURL,
+			&banner.Width, &banner.Height, &banner.Status, &banner.CreatedAt, &banner.UpdatedAt,
+			&arm.Displays, &arm.Clicks, &arm.Weight, &arm.Share); err != nil {
+			return nil, nil, err
+		}
+		banners = append(banners, banner)
+		stats = append(stats, arm)
+	}
+	return banners, stats, nil
+}
+
+// rotationBanners возвращает активные баннеры в ротации слота и их приоритеты без блокировки строк.
+func (d *databaseImpl) rotationBanners(slotID int) ([]structures.Banner, []structures.Priority, error) {
+	query := `SELECT ` + bannerColumns + `, r.weight, r.share FROM "Rotation" r
+	JOIN "Banners" b ON b.id = r.banner_id
+	WHERE r.slot_id=$1 AND r.status=$2 AND b.status=$3 AND ` + flightCondition("$4") + `
+	ORDER BY r.banner_id`
+	rows, err := d.db.Query(query, slotID, rotationActive, structures.BannerActive, d.options.currentTime())
+	if err != nil || rows.Err() != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	banners := make([]structures.Banner, 0)
+	priorities := make([]structures.Priority, 0)
+	for rows.Next() {
+		var banner structures.Banner
+		var priority structures.Priority
+		if err := rows.Scan(&banner.ID, &banner.Info, &banner.TargetURL, &banner.CreativeURL,
+			&banner.Width, &banner.Height, &banner.Status, &banner.CreatedAt, &banner.UpdatedAt,
+			&priority.Weight, &priority.Share); err != nil {
+			return nil, nil, err
+		}
+		banners = append(banners, banner)
+		priorities = append(priorities, priority)
+	}
+	return banners, priorities, nil
+}
+
+// setPriorities передает алгоритму выбора приоритеты баннеров.
+func setPriorities(stats []bannerselector.ArmStats, priorities []structures.Priority) {
+	for i, priority := range priorities {
+		stats[i].Weight, stats[i].Share = priority.Weight, priority.Share
+	}
+}
+
+func (d *databaseImpl) DatabaseAddToRotation(bannerID, slotID int) error { //nolint:stylecheck
+	return d.DatabaseAddToRotationWith(bannerID, slotID, structures.Flight{}, defaultPriority)
+}
+
+func (d *databaseImpl) DatabaseAddToRotationWith(bannerID, slotID int,
+	flight structures.Flight, priority structures.Priority,
+) error {
+	if err := validateFlight(flight); err != nil {
+		return err
+	}
+	if err := validatePriority(priority); err != nil {
+		return err
+	}
+	if err := checkEntityIsExists(d, "Banners", bannerID); err != nil {
+		return err
+	}
+	if err := checkEntityIsExists(d, "Slots", slotID); err != nil {
+		return err
+	}
+
 	tx, err := d.db.Begin()
 	if err != nil {
 		return err
@@ -26,71 +151,183 @@ func increaseDisplay(d *databaseImpl, bannerID, slotID, groupID int) error {
 		_ = tx.Rollback()
 	}()
 
-	query := `UPDATE "Statistic"
-	SET click_count = click_count + 1
-	WHERE slot_id=$1 AND group_id=$2 AND banner_id=$3`
+	shares, inRotation, err := lockSlotSharesTx(tx, slotID, bannerID)
+	if err != nil {
+		return err
+	}
+	if inRotation {
+		return ErrAlreadyInRotation
+	}
+	if err := checkShares(shares, priority); err != nil {
+		return err
+	}
 
-	_, err = tx.Exec(query, slotID, groupID, bannerID)
+	// Существующая запись не изменяется, поэтому повторное добавление безопасно
+	query := `INSERT INTO "Rotation"(banner_id, slot_id, start_at, end_at, weight, share)
+	VALUES($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (slot_id, banner_id) DO NOTHING`
+	result, err := tx.Exec(query, bannerID, slotID, flight.StartAt, flight.EndAt, priority.Weight, priority.Share)
 	if err != nil {
 		return err
 	}
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return ErrAlreadyInRotation
+	}
 	return tx.Commit()
 }
 
-func (d *databaseImpl) DatabaseAddToRotation(bannerID, slotID int) error { //nolint:stylecheck
+func (d *databaseImpl) DatabaseDeleteFromRotation(bannerID, slotID int) error {
 	if err := checkEntityIsExists(d, "Banners", bannerID); err != nil {
 		return err
 	}
+
 	if err := checkEntityIsExists(d, "Slots", slotID); err != nil {
 		return err
 	}
 
-	groups, err := d.DatabaseGetGroups()
+	// Статистика баннера удаляется каскадно
+	query := `DELETE FROM "Rotation"
+	WHERE banner_id=$1 AND slot_id=$2`
+	result, err := d.db.Exec(query, bannerID, slotID)
 	if err != nil {
 		return err
 	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return ErrNotInRotation
+	}
+	return nil
+}
 
-	tx, err := d.db.Begin()
-	if err != nil {
+// setBannerStatus изменяет статус баннера глобально (slotID = 0) или в ротации слота.
+func (d *databaseImpl) setBannerStatus(bannerID, slotID int, status string) error {
+	if err := checkEntityIsExists(d, "Banners", bannerID); err != nil {
 		return err
 	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
-
-	query := `INSERT INTO "Statistic"(banner_id, slot_id, group_id, display_count, click_count)
-	VALUES($1, $2, $3, $4, $5)`
-
-	for _, group := range groups {
-		inRotation, err := checkEntityInRotationTx(tx, bannerID, slotID, group.ID)
+	if slotID == 0 {
+		// Глобально статус переключается только между активным и приостановленным
+		query := `UPDATE "Banners" SET status = $1, updated_at = now() WHERE id = $2 AND status <> $3`
+		result, err := d.db.Exec(query, status, bannerID, structures.BannerArchived)
 		if err != nil {
 			return err
 		}
-		if inRotation {
-			return ErrAlreadyInRotation
-		}
-		_, err = tx.Exec(query, bannerID, slotID, group.ID, 0, 0)
-		if err != nil {
-			return err
+		if updated, _ := result.RowsAffected(); updated == 0 {
+			return ErrBannerArchived
 		}
+		return nil
+	}
+	if err := checkEntityIsExists(d, "Slots", slotID); err != nil {
+		return err
+	}
+	query := `UPDATE "Rotation" SET status = $1 WHERE slot_id = $2 AND banner_id = $3`
+	result, err := d.db.Exec(query, status, slotID, bannerID)
+	if err != nil {
+		return err
 	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return ErrNotInRotation
+	}
+	return nil
+}
 
-	return tx.Commit()
+func (d *databaseImpl) DatabasePauseBanner(bannerID, slotID int) error {
+	return d.setBannerStatus(bannerID, slotID, rotationPaused)
 }
 
-func (d *databaseImpl) DatabaseDeleteFromRotation(bannerID, slotID int) error {
+func (d *databaseImpl) DatabaseResumeBanner(bannerID, slotID int) error {
+	return d.setBannerStatus(bannerID, slotID, rotationActive)
+}
+
+func validateFlight(flight structures.Flight) error {
+	if flight.StartAt != nil && flight.EndAt != nil && !flight.EndAt.After(*flight.StartAt) {
+		return ErrIncorrectFlight
+	}
+	return nil
+}
+
+// flightActive проверяет, что момент t входит в период показов.
+func flightActive(flight structures.Flight, t time.Time) bool {
+	return (flight.StartAt == nil || !t.Before(*flight.StartAt)) &&
+		(flight.EndAt == nil || t.Before(*flight.EndAt))
+}
+
+func (d *databaseImpl) DatabaseSetRotationFlight(bannerID, slotID int, flight structures.Flight) error {
+	if err := validateFlight(flight); err != nil {
+		return err
+	}
 	if err := checkEntityIsExists(d, "Banners", bannerID); err != nil {
 		return err
 	}
-
 	if err := checkEntityIsExists(d, "Slots", slotID); err != nil {
 		return err
 	}
-
-	groups, err := d.DatabaseGetGroups()
+	query := `UPDATE "Rotation" SET start_at = $1, end_at = $2 WHERE slot_id = $3 AND banner_id = $4`
+	result, err := d.db.Exec(query, flight.StartAt, flight.EndAt, slotID, bannerID)
 	if err != nil {
 		return err
 	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return ErrNotInRotation
+	}
+	return nil
+}
+
+func validatePriority(priority structures.Priority) error {
+	if priority.Weight <= 0 || priority.Share < 0 || priority.Share > 1 {
+		return ErrIncorrectPriority
+	}
+	return nil
+}
+
+// checkShares проверяет, что с новой долей баннера сумма гарантированных долей слота не превышает 1.
+// defaultPriority - приоритет баннера, добавленного в ротацию без параметров.
+var defaultPriority = structures.Priority{Weight: 1}
+
+// lockSlotSharesTx блокирует слот до конца транзакции и возвращает сумму гарантированных долей
+// остальных баннеров его ротации и признак участия в ней bannerID.
+// Блокировка слота упорядочивает все изменения долей, в том числе добавление новых баннеров.
+func lockSlotSharesTx(tx *sql.Tx, slotID, bannerID int) (float64, bool, error) {
+	if _, err := tx.Exec(`SELECT id FROM "Slots" WHERE id = $1 FOR NO KEY UPDATE`, slotID); err != nil {
+		return 0, false, err
+	}
+	rows, err := tx.Query(`SELECT banner_id, share FROM "Rotation" WHERE slot_id = $1`, slotID)
+	if err != nil || rows.Err() != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+	inRotation := false
+	var shares float64
+	for rows.Next() {
+		var id int
+		var share float64
+		if err := rows.Scan(&id, &share); err != nil {
+			return 0, false, err
+		}
+		if id == bannerID {
+			inRotation = true
+		} else {
+			shares += share
+		}
+	}
+	return shares, inRotation, nil
+}
+
+func checkShares(shares float64, priority structures.Priority) error {
+	if shares+priority.Share > 1+bannerselector.ShareTolerance {
+		return ErrIncorrectPriority
+	}
+	return nil
+}
+
+func (d *databaseImpl) DatabaseSetRotationPriority(bannerID, slotID int, priority structures.Priority) error {
+	if err := validatePriority(priority); err != nil {
+		return err
+	}
+	if err := checkEntityIsExists(d, "Banners", bannerID); err != nil {
+		return err
+	}
+	if err := checkEntityIsExists(d, "Slots", slotID); err != nil {
+		return err
+	}
 
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -100,74 +337,212 @@ func (d *databaseImpl) DatabaseDeleteFromRotation(bannerID, slotID int) error {
 		_ = tx.Rollback()
 	}()
 
-	for _, group := range groups {
-		inRotation, err := checkEntityInRotationTx(tx, bannerID, slotID, group.ID)
+	// Слот блокируется, чтобы конкурентные изменения не превысили сумму долей
+	shares, inRotation, err := lockSlotSharesTx(tx, slotID, bannerID)
+	if err != nil {
+		return err
+	}
+	if !inRotation {
+		return ErrNotInRotation
+	}
+	if err := checkShares(shares, priority); err != nil {
+		return err
+	}
+
+	query := `UPDATE "Rotation" SET weight = $1, share = $2 WHERE slot_id = $3 AND banner_id = $4`
+	if _, err := tx.Exec(query, priority.Weight, priority.Share, slotID, bannerID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// selectExcept выбирает баннер алгоритмом среди баннеров, не входящих в excluded,
+// и возвращает его индекс в исходном списке. Показы исключенных баннеров учитываются
+// алгоритмом в общем числе показов.
+func selectExcept(strategy bannerselector.Strategy, banners []int, stats []bannerselector.ArmStats,
+	excluded []int,
+) (int, error) {
+	if len(excluded) == 0 {
+		return strategy.Select(stats)
+	}
+	skip := make(map[int]bool, len(excluded))
+	for _, id := range excluded {
+		skip[id] = true
+	}
+	candidates := make([]bannerselector.ArmStats, len(stats))
+	available := false
+	for i, id := range banners {
+		candidates[i] = stats[i]
+		candidates[i].Excluded = skip[id]
+		available = available || !skip[id]
+	}
+	if !available {
+		return invalidID, ErrAllExcluded
+	}
+	return strategy.Select(candidates)
+}
+
+// selectDistinct выбирает до count разных баннеров, не входящих в excluded: каждый следующий
+// выбирается алгоритмом среди оставшихся с учетом показа уже выбранных, как при отдельных
+// запросах подряд. Для вероятностных алгоритмов это последовательные случайные выборы, а не
+// баннеры с наибольшей оценкой. Если доступных баннеров меньше count, возвращаются все.
+// Статистика stats не изменяется, показы выбранных баннеров учитывает вызывающий.
+func selectDistinct(strategy bannerselector.Strategy, banners []int, stats []bannerselector.ArmStats,
+	excluded []int, count int,
+) ([]int, error) {
+	if count > 1 {
+		stats = append(make([]bannerselector.ArmStats, 0, len(stats)), stats...)
+	}
+	skip := append(make([]int, 0, len(excluded)+count), excluded...)
+	indexes := make([]int, 0, count)
+	for len(indexes) < count {
+		index, err := selectExcept(strategy, banners, stats, skip)
+		if errors.Is(err, ErrNotInRotation) && len(indexes) > 0 {
+			break
+		}
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if !inRotation {
-			return ErrNotInRotation
+		indexes = append(indexes, index)
+		skip = append(skip, banners[index])
+		if len(indexes) < count {
+			// Показ учитывается в общем числе показов и гарантированных долях для следующего выбора
+			arm := &stats[index]
+			if arm.History != nil {
+				arm.History = append(make([]bannerselector.BucketStats, 0, len(arm.History)+1), arm.History...)
+			}
+			arm.Displays++
+			countCurrentHour(arm, 1, 0)
 		}
 	}
+	return indexes, nil
+}
 
-	query := `DELETE FROM "Statistic" 
-	WHERE banner_id=$1 AND slot_id=$2`
+func firstSelected(banners []int, err error) (int, error) {
+	if err != nil {
+		return invalidID, err
+	}
+	return banners[0], nil
+}
 
-	_, err = tx.Exec(query, bannerID, slotID)
+// SelectFromRotation выбирает баннер для показа в слоте пользователю группы.
+func SelectFromRotation(db Database, slotID, groupID int) (int, error) {
+	return SelectFromRotationExcept(db, slotID, groupID, nil)
+}
+
+// SelectFromRotationExcept выбирает баннер без учета баннеров excluded.
+func SelectFromRotationExcept(db Database, slotID, groupID int, excluded []int) (int, error) {
+	return firstSelected(SelectManyFromRotation(db, slotID, groupID, 1, excluded))
+}
+
+// SelectManyFromRotation выбирает до count разных баннеров и возвращает их идентификаторы.
+func SelectManyFromRotation(db Database, slotID, groupID, count int, excluded []int) ([]int, error) {
+	selected, err := db.DatabaseSelectBanners(slotID, groupID, count, excluded)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return bannerIDs(selected.Banners), nil
+}
 
-	return tx.Commit()
+// bannerIDs возвращает идентификаторы баннеров в том же порядке.
+func bannerIDs(banners []structures.Banner) []int {
+	ids := make([]int, 0, len(banners))
+	for _, banner := range banners {
+		ids = append(ids, banner.ID)
+	}
+	return ids
+}
+
+// selectedBanners возвращает баннеры с индексами indexes в порядке выбора.
+func selectedBanners(banners []structures.Banner, indexes []int, algorithm string) structures.SelectedBanners {
+	selected := structures.SelectedBanners{Banners: make([]structures.Banner, 0, len(indexes)), Algorithm: algorithm}
+	for _, index := range indexes {
+		selected.Banners = append(selected.Banners, banners[index])
+	}
+	return selected
 }
 
-func (d *databaseImpl) DatabaseSelectFromRotation(slotID, groupID int) (bannerID int, err error) {
+func (d *databaseImpl) DatabaseSelectBanners(slotID, groupID, count int, excluded []int,
+) (structures.SelectedBanners, error) {
+	var selected structures.SelectedBanners
+	if count < 1 {
+		return selected, ErrIncorrectCount
+	}
 	if err := checkEntityIsExists(d, "Groups", groupID); err != nil {
-		return invalidID, err
+		return selected, err
 	}
-	if err := checkEntityIsExists(d, "Slots", slotID); err != nil {
-		return invalidID, err
+	slot, err := d.DatabaseGetSlot(slotID)
+	if err != nil {
+		return selected, err
 	}
 
-	query := `SELECT banner_id, display_count, click_count FROM "Statistic"
-	WHERE slot_id=$1 AND group_id=$2`
-	rows, err := d.db.Query(query, slotID, groupID)
-	if err != nil || rows.Err() != nil {
-		return invalidID, err
+	tx, err := d.db.Begin()
+	if err != nil {
+		return selected, err
 	}
-	defer rows.Close()
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	banners := make([]int, 0)
-	displays := make([]int, 0)
-	clicks := make([]int, 0)
-	for rows.Next() {
-		bannerID := int(0)
-		displayCount := int(0)
-		clickCount := int(0)
-		if err := rows.Scan(&bannerID, &displayCount, &clickCount); err != nil {
-			return invalidID, err
-		}
-		banners = append(banners, bannerID)
-		displays = append(displays, displayCount)
-		clicks = append(clicks, clickCount)
+	strategy, err := newSlotStrategy(slot.Strategy, d.options.random)
+	if err != nil {
+		return selected, err
 	}
 
+	// Чтение статистики и увеличение счетчика показов выполняются в одной транзакции
+	current := d.options.currentTime()
+	banners, stats, err := selectRotationStatsTx(tx, current, slotID, groupID)
+	if err != nil {
+		return selected, err
+	}
 	if len(banners) == 0 {
-		return invalidID, ErrNotInRotation
+		return selected, ErrNotInRotation
 	}
-
-	bannerIndex, err := bannerselector.SelectBannerIndex(displays, clicks)
+	ids := bannerIDs(banners)
+	if err := selectRotationHistoryTx(tx, current, slotID, groupID, ids, stats, historyHours(strategy)); err != nil {
+		return selected, err
+	}
+	indexes, err := selectDistinct(strategy, ids, stats, excluded, count)
 	if err != nil {
-		return invalidID, err
+		return selected, err
 	}
-	if err := increaseDisplay(d, banners[bannerIndex], slotID, groupID); err != nil {
-		return invalidID, err
+	for _, index := range indexes {
+		if err := increaseDisplayTx(tx, ids[index], slotID, groupID); err != nil {
+			return selected, err
+		}
+		key := historyKey{
+			statisticKey: statisticKey{slotID: slotID, groupID: groupID, bannerID: ids[index]},
+			bucket:       hourBucket(current),
+		}
+		if err := recordHistoryTx(tx, key, 1, 0); err != nil {
+			return selected, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return selected, err
 	}
 
-	return banners[bannerIndex], nil
+	return selectedBanners(banners, indexes, slot.Strategy.Algorithm), nil
 }
 
 func (d *databaseImpl) DatabaseRegisterTransition(slotID, bannerID, groupID int) error {
+	return d.registerTransition(slotID, bannerID, groupID, func(*sql.Tx) error {
+		return nil
+	})
+}
+
+// DatabaseRegisterImpressionTransition отмечает показ использованным и засчитывает переход
+// в одной транзакции, поэтому переход, который не удалось засчитать, не расходует показ.
+func (d *databaseImpl) DatabaseRegisterImpressionTransition(impressionID string, expiresAt time.Time,
+	slotID, bannerID, groupID int,
+) error {
+	return d.registerTransition(slotID, bannerID, groupID, func(tx *sql.Tx) error {
+		return useImpressionTx(tx, d.options.currentTime(), impressionID, expiresAt)
+	})
+}
+
+// registerTransition засчитывает переход и выполняет use в той же транзакции.
+func (d *databaseImpl) registerTransition(slotID, bannerID, groupID int, use func(tx *sql.Tx) error) error {
 	if err := checkEntityIsExists(d, "Banners", bannerID); err != nil {
 		return err
 	}
@@ -188,22 +563,98 @@ func (d *databaseImpl) DatabaseRegisterTransition(slotID, bannerID, groupID int)
 		_ = tx.Rollback()
 	}()
 
-	inRotation, err := checkEntityInRotationTx(tx, bannerID, slotID, groupID)
+	inRotation, err := checkEntityInRotationTx(tx, bannerID, slotID)
 	if err != nil {
 		return err
 	}
 	if !inRotation {
 		return ErrNotInRotation
 	}
+	if err := use(tx); err != nil {
+		return err
+	}
 
-	query := `UPDATE "Statistic"
-	SET click_count = click_count + 1
-	WHERE slot_id=$1 AND group_id=$2 AND banner_id=$3`
+	query := `INSERT INTO "Statistic"(banner_id, slot_id, group_id, display_count, click_count)
+	VALUES($1, $2, $3, 0, 1)
+	ON CONFLICT (slot_id, group_id, banner_id) DO UPDATE
+	SET click_count = "Statistic".click_count + 1`
 
-	_, err = tx.Exec(query, slotID, groupID, bannerID)
+	_, err = tx.Exec(query, bannerID, slotID, groupID)
 	if err != nil {
 		return err
 	}
+	key := historyKey{
+		statisticKey: statisticKey{slotID: slotID, groupID: groupID, bannerID: bannerID},
+		bucket:       hourBucket(d.options.currentTime()),
+	}
+	if err := recordHistoryTx(tx, key, 0, 1); err != nil {
+		return err
+	}
 
 	return tx.Commit()
 }
+
+// attachStatistics распределяет строки статистики по баннерам в ротации.
+func attachStatistics(members []structures.RotationMember, statistics []structures.Statistic) {
+	indexes := make(map[rotationMemberKey]int, len(members))
+	for i := range members {
+		members[i].Statistics = make([]structures.Statistic, 0)
+		indexes[rotationMemberKey{slotID: members[i].SlotID, bannerID: members[i].Banner.ID}] = i
+	}
+	for _, item := range statistics {
+		if i, ok := indexes[rotationMemberKey{slotID: item.SlotID, bannerID: item.BannerID}]; ok {
+			members[i].Statistics = append(members[i].Statistics, item)
+		}
+	}
+}
+
+// rotationMembers возвращает состав ротации со счетчиками, полученными через statistics.
+func (d *databaseImpl) rotationMembers(filter structures.RotationFilter,
+	statistics func(structures.StatisticFilter) ([]structures.Statistic, error),
+) ([]structures.RotationMember, error) {
+	query := `SELECT r.slot_id, r.status, r.added_at, r.start_at, r.end_at, r.weight, r.share, ` + bannerColumns + `
+	FROM "Rotation" r
+	JOIN "Banners" b ON b.id = r.banner_id
+	WHERE ($1 = 0 OR r.slot_id = $1) AND ($2 = 0 OR r.banner_id = $2)
+	ORDER BY r.slot_id, r.banner_id`
+	rows, err := d.db.Query(query, filter.SlotID, filter.BannerID)
+	if err != nil || rows.Err() != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]structures.RotationMember, 0)
+	for rows.Next() {
+		var member structures.RotationMember
+		b := &member.Banner
+		var startAt, endAt sql.NullTime
+		if err := rows.Scan(&member.SlotID, &member.Status, &member.AddedAt, &startAt, &endAt,
+			&member.Weight, &member.Share, &b.ID, &b.Info, &b.TargetURL, &b.CreativeURL, &b.Width, &b.Height, &b.Status,
+			&b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if startAt.Valid {
+			member.StartAt = &startAt.Time
+		}
+		if endAt.Valid {
+			member.EndAt = &endAt.Time
+		}
+		members = append(members, member)
+	}
+	if len(members) == 0 {
+		return members, nil
+	}
+
+	items, err := statistics(structures.StatisticFilter{SlotID: filter.SlotID, BannerID: filter.BannerID})
+	if err != nil {
+		return nil, err
+	}
+	attachStatistics(members, items)
+	return members, nil
+}
+
+func (d *databaseImpl) DatabaseGetRotationMembers(filter structures.RotationFilter) (
+	[]structures.RotationMember, error,
+) {
+	return d.rotationMembers(filter, d.DatabaseGetStatistics)
+}
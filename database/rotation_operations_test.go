@@ -2,8 +2,11 @@ package database
 
 import (
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
 	"github.com/SergeyTyurin/banner-rotation/configs"
 	"github.com/SergeyTyurin/banner-rotation/structures"
 	"github.com/stretchr/testify/require"
@@ -26,7 +29,7 @@ func setTestData(d databaseImpl) {
 }
 
 func TestDatabaseAddToRotation(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -35,24 +38,22 @@ func TestDatabaseAddToRotation(t *testing.T) {
 	setTestData(d)
 
 	t.Run("simple add", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		slotID := 1
 		bannerID := 2
 		err := d.DatabaseAddToRotation(bannerID, slotID)
 		require.NoError(t, err)
 
-		row := d.db.QueryRow(`SELECT count(*) FROM "Statistic"
+		row := d.db.QueryRow(`SELECT count(*) FROM "Rotation"
 		WHERE slot_id=$1 AND banner_id=$2`, slotID, bannerID)
 
 		count := 0
 		_ = row.Scan(&count)
-		// Test Add for each group
-		groups, _ := d.DatabaseGetGroups()
-		require.Equal(t, count, len(groups))
+		require.Equal(t, count, 1)
 	})
 
 	t.Run("add non existed intities", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 
 		err := d.DatabaseAddToRotation(20, 1)
 		require.ErrorIs(t, err, ErrNotExist)
@@ -62,17 +63,57 @@ func TestDatabaseAddToRotation(t *testing.T) {
 	})
 
 	t.Run("add already in rotation", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		err := d.DatabaseAddToRotation(1, 1)
 		require.NoError(t, err)
 
 		err = d.DatabaseAddToRotation(1, 1)
 		require.ErrorIs(t, err, ErrAlreadyInRotation)
 	})
+
+	t.Run("duplicate statistic row", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		require.NoError(t, d.DatabaseAddToRotation(1, 1))
+
+		_, err := d.db.Exec(`INSERT INTO "Statistic"(banner_id, slot_id, group_id) VALUES(1, 1, 1)`)
+		require.Error(t, err)
+	})
+
+	t.Run("group created after rotation", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		require.NoError(t, d.DatabaseAddToRotation(1, 1))
+		group, err := d.DatabaseCreateGroup(structures.Group{Info: "new group"})
+		require.NoError(t, err)
+		defer func() {
+			_ = d.DatabaseDeleteGroup(group.ID)
+		}()
+
+		require.ErrorIs(t, d.DatabaseAddToRotation(1, 1), ErrAlreadyInRotation)
+		bannerID, err := SelectFromRotation(&d, 1, group.ID)
+		require.NoError(t, err)
+		require.Equal(t, bannerID, 1)
+	})
+
+	t.Run("statistic created on first display", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		require.NoError(t, d.DatabaseAddToRotation(1, 1))
+		require.NoError(t, d.DatabaseAddToRotation(2, 1))
+
+		statisticRows := func() int {
+			count := 0
+			_ = d.db.QueryRow(`SELECT count(*) FROM "Statistic" WHERE slot_id=1`).Scan(&count)
+			return count
+		}
+		require.Equal(t, statisticRows(), 0)
+
+		_, err := SelectFromRotation(&d, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, statisticRows(), 2)
+	})
 }
 
 func TestDeleteFromRotation(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -81,7 +122,7 @@ func TestDeleteFromRotation(t *testing.T) {
 	setTestData(d)
 
 	t.Run("simple delete", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		slotID := 1
 		bannerID := 2
 		_ = d.DatabaseAddToRotation(bannerID, slotID)
@@ -96,7 +137,7 @@ func TestDeleteFromRotation(t *testing.T) {
 	})
 
 	t.Run("delete not in rotation", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		_ = d.DatabaseAddToRotation(1, 1)
 		_ = d.DatabaseAddToRotation(2, 1)
 		_ = d.DatabaseAddToRotation(1, 2)
@@ -105,8 +146,227 @@ func TestDeleteFromRotation(t *testing.T) {
 	})
 }
 
+func TestGetRotationMembers(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	_ = d.DatabaseAddToRotation(1, 1)
+	_ = d.DatabaseAddToRotation(2, 1)
+	_ = d.DatabaseAddToRotation(1, 2)
+	_, _ = SelectFromRotation(&d, 2, 1)
+
+	members, err := d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.Equal(t, 2, members[1].Banner.ID)
+	require.Equal(t, "banner_2", members[1].Banner.Info)
+	require.Equal(t, rotationActive, members[1].Status)
+	require.Len(t, members[1].Statistics, 2)
+
+	members, err = d.DatabaseGetRotationMembers(structures.RotationFilter{BannerID: 1})
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.Equal(t, 2, members[1].SlotID)
+	require.Equal(t, 1, members[1].Statistics[0].Displays)
+}
+
+func TestPauseBanner(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	_ = d.DatabaseAddToRotation(1, 1)
+	_ = d.DatabaseAddToRotation(2, 1)
+
+	require.NoError(t, d.DatabasePauseBanner(1, 1))
+	for i := 0; i < 3; i++ {
+		bannerID, err := SelectFromRotation(&d, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, 2, bannerID)
+	}
+	require.NoError(t, d.DatabaseRegisterTransition(1, 1, 1))
+
+	require.NoError(t, d.DatabasePauseBanner(2, 0))
+	_, err := SelectFromRotation(&d, 1, 1)
+	require.ErrorIs(t, err, ErrNotInRotation)
+	banner, err := d.DatabaseGetBanner(2)
+	require.NoError(t, err)
+	require.Equal(t, structures.BannerPaused, banner.Status)
+
+	require.NoError(t, d.DatabaseResumeBanner(1, 1))
+	require.NoError(t, d.DatabaseResumeBanner(2, 0))
+	statistics, err := d.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 2})
+	require.NoError(t, err)
+	require.Equal(t, 3, statistics[0].Displays)
+
+	archived, _ := d.DatabaseCreateBanner(structures.Banner{Status: structures.BannerArchived})
+	require.ErrorIs(t, d.DatabasePauseBanner(archived.ID, 0), ErrBannerArchived)
+	require.ErrorIs(t, d.DatabaseResumeBanner(archived.ID, 0), ErrBannerArchived)
+	archived, _ = d.DatabaseGetBanner(archived.ID)
+	require.Equal(t, structures.BannerArchived, archived.Status)
+
+	require.ErrorIs(t, d.DatabasePauseBanner(1, 3), ErrNotInRotation)
+	require.ErrorIs(t, d.DatabasePauseBanner(100, 0), ErrNotExist)
+}
+
+func TestRotationFlight(t *testing.T) {
+	current := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := databaseImpl{db: nil, options: newOptions([]Option{WithClock(func() time.Time { return current })})}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	startAt, endAt := current.Add(time.Hour), current.Add(2*time.Hour)
+	_ = d.DatabaseAddToRotation(1, 1)
+	require.NoError(t, d.DatabaseSetRotationFlight(1, 1, structures.Flight{StartAt: &startAt, EndAt: &endAt}))
+
+	_, err := SelectFromRotation(&d, 1, 1)
+	require.ErrorIs(t, err, ErrNotInRotation)
+	current = startAt
+	bannerID, err := SelectFromRotation(&d, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, bannerID)
+	current = endAt
+	_, err = SelectFromRotation(&d, 1, 1)
+	require.ErrorIs(t, err, ErrNotInRotation)
+
+	members, err := d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+	require.NoError(t, err)
+	require.True(t, startAt.Equal(*members[0].StartAt))
+	require.True(t, endAt.Equal(*members[0].EndAt))
+
+	require.ErrorIs(t, d.DatabaseSetRotationFlight(1, 1, structures.Flight{StartAt: &endAt, EndAt: &startAt}),
+		ErrIncorrectFlight)
+	require.ErrorIs(t, d.DatabaseSetRotationFlight(2, 1, structures.Flight{}), ErrNotInRotation)
+}
+
+func TestAddToRotationWith(t *testing.T) {
+	current := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	d := databaseImpl{db: nil, options: newOptions([]Option{WithClock(func() time.Time { return current })})}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	startAt := current.Add(time.Hour)
+	priority := structures.Priority{Weight: 2, Share: 0.6}
+
+	require.NoError(t, d.DatabaseAddToRotationWith(1, 1, structures.Flight{StartAt: &startAt}, priority))
+	_, err := SelectFromRotation(&d, 1, 1)
+	require.ErrorIs(t, err, ErrNotInRotation)
+	current = startAt
+	bannerID, err := SelectFromRotation(&d, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, bannerID)
+	members, err := d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+	require.NoError(t, err)
+	require.Equal(t, priority, members[0].Priority)
+
+	// некорректные параметры не добавляют баннер в ротацию
+	require.ErrorIs(t, d.DatabaseAddToRotationWith(2, 1, structures.Flight{},
+		structures.Priority{Weight: 1, Share: 0.5}), ErrIncorrectPriority)
+	members, _ = d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: 1})
+	require.Len(t, members, 1)
+	require.ErrorIs(t, d.DatabaseAddToRotationWith(1, 1, structures.Flight{}, defaultPriority), ErrAlreadyInRotation)
+}
+
+func TestRotationPriority(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	_ = d.DatabaseAddToRotation(1, 1)
+	_ = d.DatabaseAddToRotation(2, 1)
+	_ = d.DatabaseAddToRotation(3, 1)
+	require.NoError(t, d.DatabaseSetRotationPriority(3, 1, structures.Priority{Weight: 2, Share: 0.5}))
+	for i := 0; i < 20; i++ {
+		_, err := SelectFromRotation(&d, 1, 1)
+		require.NoError(t, err)
+	}
+	statistics, err := d.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 3})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, statistics[0].Displays, 10)
+
+	members, err := d.DatabaseGetRotationMembers(structures.RotationFilter{BannerID: 3})
+	require.NoError(t, err)
+	require.Equal(t, structures.Priority{Weight: 2, Share: 0.5}, members[0].Priority)
+
+	require.ErrorIs(t, d.DatabaseSetRotationPriority(1, 1, structures.Priority{Weight: 1, Share: 0.6}),
+		ErrIncorrectPriority)
+	require.ErrorIs(t, d.DatabaseSetRotationPriority(1, 1, structures.Priority{}), ErrIncorrectPriority)
+	require.ErrorIs(t, d.DatabaseSetRotationPriority(4, 1, structures.Priority{Weight: 1}), ErrNotInRotation)
+}
+
+func TestSelectManyFromRotation(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+	_ = d.DatabaseAddToRotation(1, 1)
+	_ = d.DatabaseAddToRotation(2, 1)
+	_ = d.DatabaseAddToRotation(3, 1)
+
+	banners, err := SelectManyFromRotation(&d, 1, 1, 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, banners)
+	banners, err = SelectManyFromRotation(&d, 1, 1, 5, []int{1})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int{2, 3}, banners)
+
+	statistics, err := d.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 2})
+	require.NoError(t, err)
+	require.Equal(t, 2, statistics[0].Displays)
+
+	_, err = SelectManyFromRotation(&d, 1, 1, 0, nil)
+	require.ErrorIs(t, err, ErrIncorrectCount)
+}
+
+func TestSelectDistinct(t *testing.T) {
+	strategy, err := bannerselector.NewStrategy(bannerselector.UCB1, bannerselector.Params{})
+	require.NoError(t, err)
+	stats := []bannerselector.ArmStats{
+		{Displays: 3, Clicks: 3},
+		{Displays: 3, Clicks: 0, Share: 0.3},
+		{Displays: 3, Clicks: 1},
+	}
+	// после показа первого баннера второй отстает от гарантированной доли
+	indexes, err := selectDistinct(strategy, []int{1, 2, 3}, stats, nil, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, indexes)
+	require.Equal(t, 3, stats[0].Displays)
+
+	indexes, err = selectDistinct(strategy, []int{1, 2, 3}, stats, []int{1, 3}, 2)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, indexes)
+
+	_, err = selectDistinct(strategy, []int{1, 2, 3}, stats, []int{1, 2, 3}, 2)
+	require.ErrorIs(t, err, ErrNotInRotation)
+}
+
 func TestSelectFromRotation(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -115,26 +375,26 @@ func TestSelectFromRotation(t *testing.T) {
 	setTestData(d)
 
 	t.Run("existing select", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		_ = d.DatabaseAddToRotation(1, 1)
 		_ = d.DatabaseAddToRotation(2, 1)
-		groups, _ := d.DatabaseGetGroups()
+		groups, _ := d.DatabaseGetGroups(structures.ListParams{})
 		for _, group := range groups {
-			bannerID, err := d.DatabaseSelectFromRotation(1, group.ID)
+			bannerID, err := SelectFromRotation(&d, 1, group.ID)
 			require.NoError(t, err)
 			require.Equal(t, bannerID, 1)
 
-			bannerID, err = d.DatabaseSelectFromRotation(1, group.ID)
+			bannerID, err = SelectFromRotation(&d, 1, group.ID)
 			require.NoError(t, err)
 			require.Equal(t, bannerID, 2)
 		}
 	})
 
 	t.Run("non existing select", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
-		groups, _ := d.DatabaseGetGroups()
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+		groups, _ := d.DatabaseGetGroups(structures.ListParams{})
 		for _, group := range groups {
-			bannerID, notInError := d.DatabaseSelectFromRotation(1, group.ID)
+			bannerID, notInError := SelectFromRotation(&d, 1, group.ID)
 			require.Error(t, notInError)
 			require.Equal(t, bannerID, invalidID)
 		}
@@ -142,7 +402,7 @@ func TestSelectFromRotation(t *testing.T) {
 }
 
 func TestRegisterTransition(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -151,10 +411,10 @@ func TestRegisterTransition(t *testing.T) {
 	setTestData(d)
 
 	t.Run("simple register", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		_ = d.DatabaseAddToRotation(2, 1)
 
-		groups, _ := d.DatabaseGetGroups()
+		groups, _ := d.DatabaseGetGroups(structures.ListParams{})
 		for _, group := range groups {
 			err := d.DatabaseRegisterTransition(1, 2, group.ID)
 			require.NoError(t, err)
@@ -167,10 +427,10 @@ func TestRegisterTransition(t *testing.T) {
 	})
 
 	t.Run("register for not in rotation", func(t *testing.T) {
-		_, _ = d.db.Exec(`TRUNCATE TABLE "Statistic" RESTART IDENTITY CASCADE`)
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
 		_ = d.DatabaseAddToRotation(1, 2)
 
-		groups, _ := d.DatabaseGetGroups()
+		groups, _ := d.DatabaseGetGroups(structures.ListParams{})
 		for _, group := range groups {
 			err := d.DatabaseRegisterTransition(1, 1, group.ID)
 			require.ErrorIs(t, err, ErrNotInRotation)
@@ -182,3 +442,99 @@ func TestRegisterTransition(t *testing.T) {
 		require.ErrorIs(t, err, ErrNotInRotation)
 	})
 }
+
+func TestDisplayAndClickCounters(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+
+	slotID, groupID := 1, 1
+	_ = d.DatabaseAddToRotation(1, slotID)
+	_ = d.DatabaseAddToRotation(2, slotID)
+
+	selections := 10
+	selected := make(map[int]int)
+	for i := 0; i < selections; i++ {
+		bannerID, err := SelectFromRotation(&d, slotID, groupID)
+		require.NoError(t, err)
+		selected[bannerID]++
+	}
+	require.NoError(t, d.DatabaseRegisterTransition(slotID, 1, groupID))
+	require.NoError(t, d.DatabaseRegisterTransition(slotID, 1, groupID))
+
+	counters := func(bannerID int) (int, int) {
+		displays, clicks := 0, 0
+		_ = d.db.QueryRow(`SELECT display_count, click_count FROM "Statistic"
+	WHERE slot_id=$1 AND banner_id=$2 AND group_id=$3`, slotID, bannerID, groupID).Scan(&displays, &clicks)
+		return displays, clicks
+	}
+
+	displays, clicks := counters(1)
+	require.Equal(t, displays, selected[1])
+	require.Equal(t, clicks, 2)
+
+	displays, clicks = counters(2)
+	require.Equal(t, displays, selected[2])
+	require.Equal(t, clicks, 0)
+	require.Equal(t, selections, selected[1]+selected[2])
+
+	// Статистика другой группы не изменилась
+	otherDisplays := 0
+	_ = d.db.QueryRow(`SELECT sum(display_count) FROM "Statistic"
+	WHERE slot_id=$1 AND group_id<>$2`, slotID, groupID).Scan(&otherDisplays)
+	require.Equal(t, otherDisplays, 0)
+}
+
+func TestConcurrentSelectFromRotation(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+
+	slotID, groupID := 1, 1
+	banners := []int{1, 2, 3}
+	for _, bannerID := range banners {
+		_ = d.DatabaseAddToRotation(bannerID, slotID)
+	}
+
+	workers, perWorker := 20, 15
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				_, err := SelectFromRotation(&d, slotID, groupID)
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	total := 0
+	_ = d.db.QueryRow(`SELECT sum(display_count) FROM "Statistic"
+	WHERE slot_id=$1 AND group_id=$2`, slotID, groupID).Scan(&total)
+	require.Equal(t, workers*perWorker, total)
+
+	// Без переходов UCB1 показывает баннеры поровну, если каждый выбор видит актуальные счетчики
+	for _, bannerID := range banners {
+		displays := 0
+		_ = d.db.QueryRow(`SELECT display_count FROM "Statistic"
+	WHERE slot_id=$1 AND banner_id=$2 AND group_id=$3`, slotID, bannerID, groupID).Scan(&displays)
+		require.Equal(t, workers*perWorker/len(banners), displays)
+	}
+}
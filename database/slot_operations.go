@@ -1,10 +1,83 @@
 package database
 
-import "github.com/SergeyTyurin/banner-rotation/structures"
+import (
+	"fmt"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+func strategyParams(strategy structures.Strategy) bannerselector.Params {
+	return bannerselector.Params{
+		Epsilon:     strategy.Epsilon,
+		Exploration: strategy.Exploration,
+		Temperature: strategy.Temperature,
+		Alpha:       strategy.Alpha,
+		Beta:        strategy.Beta,
+		Discount:    strategy.Discount,
+		Window:      strategy.Window,
+	}
+}
+
+func newSlotStrategy(strategy structures.Strategy, random bannerselector.Random) (bannerselector.Strategy, error) {
+	params := strategyParams(strategy)
+	params.Random = random
+	return bannerselector.NewStrategy(strategy.Algorithm, params)
+}
+
+// maxHistoryHours - наибольшая глубина почасовой истории, которую алгоритм слота читает при выборе (90 дней).
+const maxHistoryHours = 24 * 90
+
+// validateStrategy проверяет настройки алгоритма слота и подставляет алгоритм по умолчанию.
+func validateStrategy(strategy *structures.Strategy) error {
+	if strategy.Algorithm == "" {
+		strategy.Algorithm = bannerselector.DefaultAlgorithm
+	}
+	if d := strategy.Discount; d != nil && (*d <= 0 || *d >= 1) {
+		return fmt.Errorf("%w: discount must be between 0 and 1", ErrIncorrectEntity)
+	}
+	if w := strategy.Window; w != nil && *w > maxHistoryHours {
+		return fmt.Errorf("%w: window exceeds %d hours", ErrIncorrectEntity, maxHistoryHours)
+	}
+	selector, err := newSlotStrategy(*strategy, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrIncorrectStrategy, err)
+	}
+	// Затухание, близкое к единице, читает историю глубже допустимого окна
+	if historyHours(selector) > maxHistoryHours {
+		return fmt.Errorf("%w: history exceeds %d hours", ErrIncorrectEntity, maxHistoryHours)
+	}
+	return nil
+}
 
-func (d *databaseImpl) DatabaseGetSlots() ([]structures.Slot, error) {
-	query := `SELECT id, info FROM "Slots"`
-	rows, err := d.db.Query(query)
+// validateSlot проверяет размер и алгоритм слота.
+func validateSlot(slot *structures.Slot) error {
+	if slot.Width < 0 || slot.Height < 0 {
+		return fmt.Errorf("%w: negative size", ErrIncorrectEntity)
+	}
+	return validateStrategy(&slot.Strategy)
+}
+
+// slotColumns - столбцы слота в порядке scanSlot.
+const slotColumns = `id, info, width, height, placement,
+	algorithm, epsilon, exploration, temperature, alpha, beta, discount, "window"`
+
+func scanSlot(row rowScanner) (structures.Slot, error) {
+	var slot structures.Slot
+	s := &slot.Strategy
+	err := row.Scan(&slot.ID, &slot.Info, &slot.Width, &slot.Height, &slot.Placement,
+		&s.Algorithm, &s.Epsilon, &s.Exploration, &s.Temperature, &s.Alpha, &s.Beta,
+		&s.Discount, &s.Window)
+	return slot, err
+}
+
+func (d *databaseImpl) DatabaseGetSlots(params structures.ListParams) ([]structures.Slot, error) {
+	if err := validateListParams(&params); err != nil {
+		return nil, err
+	}
+	condition, args := listCondition(params)
+	query := `SELECT ` + slotColumns + ` FROM "Slots" ` + condition
+	rows, err := d.db.Query(query, args...)
 	if err != nil || rows.Err() != nil {
 		return nil, err
 	}
@@ -12,32 +85,29 @@ func (d *databaseImpl) DatabaseGetSlots() ([]structures.Slot, error) {
 
 	slots := make([]structures.Slot, 0)
 	for rows.Next() {
-		var id int
-		var info string
-		if err := rows.Scan(&id, &info); err != nil {
+		slot, err := scanSlot(rows)
+		if err != nil {
 			return nil, err
 		}
-		slots = append(slots, structures.Slot{ID: id, Info: info})
+		slots = append(slots, slot)
 	}
 	return slots, nil
 }
 
 func (d *databaseImpl) DatabaseGetSlot(id int) (structures.Slot, error) {
-	query := `SELECT info FROM "Slots" WHERE id = $1`
-	row := d.db.QueryRow(query, id)
-
-	var info string
-	if err := row.Scan(&info); err != nil {
+	query := `SELECT ` + slotColumns + ` FROM "Slots" WHERE id = $1`
+	slot, err := scanSlot(d.db.QueryRow(query, id))
+	if err != nil {
 		return structures.Slot{ID: invalidID}, ErrNotExist
 	}
-	return structures.Slot{ID: id, Info: info}, nil
+	return slot, nil
 }
 
 func (d *databaseImpl) DatabaseDeleteSlot(id int) error {
 	if err := checkEntityIsExists(d, "Slots", id); err != nil {
 		return err
 	}
-	rotationQuery := `DELETE FROM "Statistic" WHERE Slot_id = $1`
+	rotationQuery := `DELETE FROM "Rotation" WHERE slot_id = $1`
 	query := `DELETE FROM "Slots" WHERE id = $1`
 
 	tx, err := d.db.Begin()
@@ -66,7 +136,12 @@ func (d *databaseImpl) DatabaseDeleteSlot(id int) error {
 }
 
 func (d *databaseImpl) DatabaseCreateSlot(entity structures.Slot) (structures.Slot, error) {
-	query := `INSERT INTO "Slots" (info) VALUES($1)
+	if err := validateSlot(&entity); err != nil {
+		return structures.Slot{ID: invalidID}, err
+	}
+	query := `INSERT INTO "Slots" (info, width, height, placement,
+	algorithm, epsilon, exploration, temperature, alpha, beta, discount, "window")
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	RETURNING id`
 	tx, err := d.db.Begin()
 	if err != nil {
@@ -76,7 +151,9 @@ func (d *databaseImpl) DatabaseCreateSlot(entity structures.Slot) (structures.Sl
 		_ = tx.Rollback()
 	}()
 
-	row := tx.QueryRow(query, entity.Info)
+	s := entity.Strategy
+	row := tx.QueryRow(query, entity.Info, entity.Width, entity.Height, entity.Placement,
+		s.Algorithm, s.Epsilon, s.Exploration, s.Temperature, s.Alpha, s.Beta, s.Discount, s.Window)
 	id := invalidID
 	if err := row.Scan(&id); err != nil {
 		return structures.Slot{ID: invalidID}, err
@@ -85,16 +162,22 @@ func (d *databaseImpl) DatabaseCreateSlot(entity structures.Slot) (structures.Sl
 	if err := tx.Commit(); err != nil {
 		return structures.Slot{ID: invalidID}, err
 	}
-	return structures.Slot{ID: id, Info: entity.Info}, nil
+	entity.ID = id
+	return entity, nil
 }
 
 func (d *databaseImpl) DatabaseUpdateSlot(entity structures.Slot) error {
 	if err := checkEntityIsExists(d, "Slots", entity.ID); err != nil {
 		return err
 	}
+	if err := validateSlot(&entity); err != nil {
+		return err
+	}
 	query := `UPDATE "Slots"
-	SET info = $1
-	WHERE id = $2`
+	SET info = $1, width = $2, height = $3, placement = $4,
+	algorithm = $5, epsilon = $6, exploration = $7, temperature = $8, alpha = $9, beta = $10,
+	discount = $11, "window" = $12
+	WHERE id = $13`
 	tx, err := d.db.Begin()
 	if err != nil {
 		return err
@@ -103,7 +186,9 @@ func (d *databaseImpl) DatabaseUpdateSlot(entity structures.Slot) error {
 		_ = tx.Rollback()
 	}()
 
-	res, err := tx.Exec(query, entity.Info, entity.ID)
+	s := entity.Strategy
+	res, err := tx.Exec(query, entity.Info, entity.Width, entity.Height, entity.Placement,
+		s.Algorithm, s.Epsilon, s.Exploration, s.Temperature, s.Alpha, s.Beta, s.Discount, s.Window, entity.ID)
 	if err != nil {
 		return err
 	}
@@ -4,13 +4,14 @@ import (
 	"strconv"
 	"testing"
 
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
 	"github.com/SergeyTyurin/banner-rotation/configs"
 	"github.com/SergeyTyurin/banner-rotation/structures"
 	"github.com/stretchr/testify/require"
 )
 
 func TestCreateSlot(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -29,7 +30,7 @@ func TestCreateSlot(t *testing.T) {
 }
 
 func TestGetSlots(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -44,7 +45,7 @@ func TestGetSlots(t *testing.T) {
 			_, _ = d.DatabaseCreateSlot(slot)
 		}
 
-		slots, err := d.DatabaseGetSlots()
+		slots, err := d.DatabaseGetSlots(structures.ListParams{})
 		require.NoError(t, err)
 		require.Equal(t, len(slots), count)
 	})
@@ -64,7 +65,7 @@ func TestGetSlots(t *testing.T) {
 
 	t.Run("get from empty", func(t *testing.T) {
 		_, _ = d.db.Exec(`TRUNCATE TABLE "Slots" RESTART IDENTITY CASCADE`)
-		slots, err := d.DatabaseGetSlots()
+		slots, err := d.DatabaseGetSlots(structures.ListParams{})
 		require.NoError(t, err)
 		require.Empty(t, slots)
 
@@ -76,7 +77,7 @@ func TestGetSlots(t *testing.T) {
 }
 
 func TestUpdateSlot(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -104,7 +105,7 @@ func TestUpdateSlot(t *testing.T) {
 }
 
 func TestDeleteSlot(t *testing.T) {
-	d := databaseImpl{nil}
+	d := databaseImpl{db: nil}
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
@@ -125,7 +126,63 @@ func TestDeleteSlot(t *testing.T) {
 		err := d.DatabaseDeleteSlot(newSlot.ID)
 		require.NoError(t, err)
 
-		slots, _ := d.DatabaseGetSlots()
+		slots, _ := d.DatabaseGetSlots(structures.ListParams{})
 		require.Empty(t, slots)
 	})
 }
+
+func TestSlotStrategy(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+
+	t.Run("default strategy", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Slots" RESTART IDENTITY CASCADE`)
+		newSlot, err := d.DatabaseCreateSlot(structures.Slot{Info: "info"})
+		require.NoError(t, err)
+		require.Equal(t, newSlot.Strategy.Algorithm, bannerselector.DefaultAlgorithm)
+
+		slot, _ := d.DatabaseGetSlot(newSlot.ID)
+		require.Equal(t, slot.Strategy.Algorithm, bannerselector.DefaultAlgorithm)
+	})
+
+	t.Run("create and update strategy", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Slots" RESTART IDENTITY CASCADE`)
+		strategy := structures.Strategy{Algorithm: bannerselector.EpsilonGreedy, Epsilon: ptr(0.2)}
+		newSlot, err := d.DatabaseCreateSlot(structures.Slot{Info: "info", Strategy: strategy})
+		require.NoError(t, err)
+
+		slot, _ := d.DatabaseGetSlot(newSlot.ID)
+		require.Equal(t, slot.Strategy, strategy)
+
+		slot.Strategy = structures.Strategy{Algorithm: bannerselector.ThompsonSampling, Alpha: ptr(2.0), Beta: ptr(30.0)}
+		err = d.DatabaseUpdateSlot(slot)
+		require.NoError(t, err)
+
+		updated, _ := d.DatabaseGetSlot(newSlot.ID)
+		require.Equal(t, updated.Strategy, slot.Strategy)
+
+		// заданный нулевой параметр сохраняется и отличается от незаданного
+		slot.Strategy = structures.Strategy{Algorithm: bannerselector.EpsilonGreedy, Epsilon: ptr(0.0)}
+		require.NoError(t, d.DatabaseUpdateSlot(slot))
+		updated, _ = d.DatabaseGetSlot(newSlot.ID)
+		require.Equal(t, slot.Strategy, updated.Strategy)
+		require.Nil(t, updated.Strategy.Exploration)
+	})
+
+	t.Run("incorrect strategy", func(t *testing.T) {
+		_, _ = d.db.Exec(`TRUNCATE TABLE "Slots" RESTART IDENTITY CASCADE`)
+		slot := structures.Slot{Info: "info", Strategy: structures.Strategy{Algorithm: "unknown"}}
+		_, err := d.DatabaseCreateSlot(slot)
+		require.ErrorIs(t, err, ErrIncorrectStrategy)
+
+		newSlot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "info"})
+		newSlot.Strategy.Epsilon = ptr(5.0)
+		newSlot.Strategy.Algorithm = bannerselector.EpsilonGreedy
+		err = d.DatabaseUpdateSlot(newSlot)
+		require.ErrorIs(t, err, ErrIncorrectStrategy)
+	})
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"sort"
+
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+// scoreStatistics вычисляет CTR и оценку UCB1 каждого баннера среди баннеров той же пары слот/группа
+// и оставляет строки, подходящие под фильтр по баннеру.
+// exploration - параметр исследования UCB1 для каждого слота.
+func scoreStatistics(items []structures.Statistic, exploration map[int]*float64,
+	bannerID int,
+) []structures.Statistic {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if a.SlotID != b.SlotID {
+			return a.SlotID < b.SlotID
+		}
+		if a.GroupID != b.GroupID {
+			return a.GroupID < b.GroupID
+		}
+		return a.BannerID < b.BannerID
+	})
+
+	result := make([]structures.Statistic, 0, len(items))
+	for start := 0; start < len(items); {
+		end := start
+		for end < len(items) && items[end].SlotID == items[start].SlotID &&
+			items[end].GroupID == items[start].GroupID {
+			end++
+		}
+
+		stats := make([]bannerselector.ArmStats, 0, end-start)
+		for _, item := range items[start:end] {
+			stats = append(stats, bannerselector.ArmStats{Displays: item.Displays, Clicks: item.Clicks})
+		}
+		for i, item := range items[start:end] {
+			if bannerID != 0 && item.BannerID != bannerID {
+				continue
+			}
+			item.CTR = stats[i].CTR()
+			if score, ok := bannerselector.UCB1Score(stats, i, exploration[item.SlotID]); ok {
+				item.Score = &score
+			}
+			result = append(result, item)
+		}
+		start = end
+	}
+	return result
+}
+
+// statisticRows возвращает счетчики всех баннеров в ротации для каждой группы
+// без учета фильтра по баннеру, а также параметры исследования слотов.
+func (d *databaseImpl) statisticRows(filter structures.StatisticFilter) (
+	[]structures.Statistic, map[int]*float64, error,
+) {
+	query := `SELECT r.slot_id, g.id, r.banner_id,
+		coalesce(s.display_count, 0), coalesce(s.click_count, 0), sl.exploration
+	FROM "Rotation" r
+	JOIN "Slots" sl ON sl.id = r.slot_id
+	CROSS JOIN "Groups" g
+	LEFT JOIN "Statistic" s ON s.slot_id = r.slot_id AND s.banner_id = r.banner_id AND s.group_id = g.id
+	WHERE ($1 = 0 OR r.slot_id = $1) AND ($2 = 0 OR g.id = $2)`
+	rows, err := d.db.Query(query, filter.SlotID, filter.GroupID)
+	if err != nil || rows.Err() != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	items := make([]structures.Statistic, 0)
+	exploration := make(map[int]*float64)
+	for rows.Next() {
+		var item structures.Statistic
+		var slotExploration *float64
+		if err := rows.Scan(&item.SlotID, &item.GroupID, &item.BannerID,
+			&item.Displays, &item.Clicks, &slotExploration); err != nil {
+			return nil, nil, err
+		}
+		items = append(items, item)
+		exploration[item.SlotID] = slotExploration
+	}
+	return items, exploration, nil
+}
+
+func (d *databaseImpl) DatabaseGetStatistics(filter structures.StatisticFilter) ([]structures.Statistic, error) {
+	items, exploration, err := d.statisticRows(filter)
+	if err != nil {
+		return nil, err
+	}
+	return scoreStatistics(items, exploration, filter.BannerID), nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreStatistics(t *testing.T) {
+	items := []structures.Statistic{
+		{SlotID: 1, GroupID: 2, BannerID: 1, Displays: 5},
+		{SlotID: 1, GroupID: 1, BannerID: 2, Displays: 10, Clicks: 1},
+		{SlotID: 1, GroupID: 1, BannerID: 1, Displays: 10, Clicks: 5},
+		{SlotID: 1, GroupID: 1, BannerID: 3},
+	}
+
+	t.Run("all", func(t *testing.T) {
+		scored := scoreStatistics(append([]structures.Statistic(nil), items...), map[int]*float64{}, 0)
+		require.Len(t, scored, 4)
+		require.Equal(t, []int{1, 2, 3, 1}, []int{
+			scored[0].BannerID, scored[1].BannerID, scored[2].BannerID, scored[3].BannerID,
+		})
+		require.InDelta(t, 0.5, scored[0].CTR, 1e-9)
+		require.Greater(t, *scored[0].Score, *scored[1].Score)
+		require.Nil(t, scored[2].Score)
+		// Оценка считается только по баннерам своей группы
+		require.InDelta(t, math.Sqrt(2*math.Log(5)/5), *scored[3].Score, 1e-9)
+	})
+
+	t.Run("filter by banner", func(t *testing.T) {
+		scored := scoreStatistics(append([]structures.Statistic(nil), items...), map[int]*float64{1: ptr(0.5)}, 2)
+		require.Len(t, scored, 1)
+		require.Equal(t, 2, scored[0].BannerID)
+		require.NotNil(t, scored[0].Score)
+	})
+}
+
+func TestGetStatistics(t *testing.T) {
+	d := databaseImpl{db: nil}
+	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	closeConnection, _ := d.DatabaseConnect(config)
+	defer func() {
+		_ = closeConnection()
+	}()
+	setTestData(d)
+	_, _ = d.db.Exec(`TRUNCATE TABLE "Rotation" RESTART IDENTITY CASCADE`)
+
+	_ = d.DatabaseAddToRotation(1, 1)
+	_ = d.DatabaseAddToRotation(2, 1)
+	_ = d.DatabaseAddToRotation(1, 2)
+	for i := 0; i < 4; i++ {
+		_, _ = SelectFromRotation(&d, 1, 1)
+	}
+	_ = d.DatabaseRegisterTransition(1, 1, 1)
+
+	t.Run("all", func(t *testing.T) {
+		statistics, err := d.DatabaseGetStatistics(structures.StatisticFilter{})
+		require.NoError(t, err)
+		// 3 баннера в ротации для 2 групп
+		require.Len(t, statistics, 6)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		statistics, err := d.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 1, BannerID: 1})
+		require.NoError(t, err)
+		require.Len(t, statistics, 1)
+		require.Equal(t, statistics[0].Displays, 2)
+		require.Equal(t, statistics[0].Clicks, 1)
+		require.NotNil(t, statistics[0].Score)
+
+		statistics, err = d.DatabaseGetStatistics(structures.StatisticFilter{SlotID: 1, GroupID: 2})
+		require.NoError(t, err)
+		require.Len(t, statistics, 2)
+		require.Nil(t, statistics[0].Score)
+	})
+}
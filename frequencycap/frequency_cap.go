@@ -0,0 +1,122 @@
+package frequencycap
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	ErrIncorrectParams = errors.New("incorrect frequency cap params")
+	ErrCapped          = errors.New("all banners reached the frequency cap")
+)
+
+// now - источник текущего времени для учета показов.
+var now = time.Now
+
+// Store хранит моменты показов баннеров пользователям.
+// Реализация должна быть безопасной для конкурентного использования.
+type Store interface {
+	// Add учитывает показ баннера пользователю в момент at.
+	Add(userID string, bannerID int, at time.Time) error
+	// Counts возвращает число показов каждого баннера пользователю начиная с момента since.
+	Counts(userID string, since time.Time) (map[int]int, error)
+}
+
+// Capper ограничивает число показов баннера одному пользователю за скользящее окно.
+type Capper interface {
+	// Capped возвращает баннеры, достигшие ограничения для пользователя.
+	Capped(userID string) ([]int, error)
+	// Record учитывает показ баннера пользователю.
+	Record(userID string, bannerID int) error
+	// Select выбирает баннеры для пользователя функцией choose, передавая ей баннеры,
+	// достигшие ограничения, и учитывает показ выбранных. Выборы для одного пользователя
+	// выполняются последовательно, поэтому конкурентные запросы не превышают ограничение.
+	Select(userID string, choose func(excluded []int) ([]int, error)) ([]int, error)
+}
+
+type capperImpl struct {
+	store  Store
+	limit  int
+	window time.Duration
+
+	// users - блокировки пользователей, выбор для которых выполняется сейчас
+	usersMu sync.Mutex
+	users   map[string]*userLock
+}
+
+type userLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+// NewCapper создает ограничение не более limit показов баннера пользователю за window.
+func NewCapper(store Store, limit int, window time.Duration) (Capper, error) {
+	if store == nil || limit <= 0 || window <= 0 {
+		return nil, ErrIncorrectParams
+	}
+	return &capperImpl{store: store, limit: limit, window: window, users: make(map[string]*userLock)}, nil
+}
+
+func (c *capperImpl) Capped(userID string) ([]int, error) {
+	counts, err := c.store.Counts(userID, now().Add(-c.window))
+	if err != nil {
+		return nil, err
+	}
+	capped := make([]int, 0)
+	for bannerID, count := range counts {
+		if count >= c.limit {
+			capped = append(capped, bannerID)
+		}
+	}
+	return capped, nil
+}
+
+func (c *capperImpl) Record(userID string, bannerID int) error {
+	return c.store.Add(userID, bannerID, now())
+}
+
+func (c *capperImpl) Select(userID string, choose func(excluded []int) ([]int, error)) ([]int, error) {
+	unlock := c.lock(userID)
+	defer unlock()
+	excluded, err := c.Capped(userID)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := choose(excluded)
+	if err != nil {
+		return nil, err
+	}
+	// Баннер уже показан, поэтому ошибка учета не отменяет выбор
+	for _, bannerID := range selected {
+		if err := c.Record(userID, bannerID); err != nil {
+			log.Println("frequency cap:", err)
+		}
+	}
+	return selected, nil
+}
+
+// lock блокирует выбор для пользователя и возвращает функцию снятия блокировки.
+// Блокировка удаляется, когда ее не ждет ни один запрос.
+func (c *capperImpl) lock(userID string) func() {
+	c.usersMu.Lock()
+	user, ok := c.users[userID]
+	if !ok {
+		user = &userLock{}
+		c.users[userID] = user
+	}
+	user.waiters++
+	c.usersMu.Unlock()
+
+	user.mu.Lock()
+	return func() {
+		user.mu.Unlock()
+		c.usersMu.Lock()
+		defer c.usersMu.Unlock()
+		user.waiters--
+		if user.waiters == 0 {
+			delete(c.users, userID)
+		}
+	}
+}
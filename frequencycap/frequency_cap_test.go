@@ -0,0 +1,87 @@
+package frequencycap
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setNow(t *testing.T, current *time.Time) {
+	t.Helper()
+	now = func() time.Time {
+		return *current
+	}
+	t.Cleanup(func() {
+		now = time.Now
+	})
+}
+
+func TestNewCapper(t *testing.T) {
+	_, err := NewCapper(nil, 1, time.Hour)
+	require.ErrorIs(t, err, ErrIncorrectParams)
+	_, err = NewCapper(NewMemoryStore(time.Hour), 0, time.Hour)
+	require.ErrorIs(t, err, ErrIncorrectParams)
+	_, err = NewCapper(NewMemoryStore(time.Hour), 1, 0)
+	require.ErrorIs(t, err, ErrIncorrectParams)
+}
+
+func TestCapper(t *testing.T) {
+	current := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	setNow(t, &current)
+	capper, err := NewCapper(NewMemoryStore(time.Hour), 2, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, capper.Record("user", 1))
+	require.NoError(t, capper.Record("user", 2))
+	current = current.Add(30 * time.Minute)
+	require.NoError(t, capper.Record("user", 1))
+
+	capped, err := capper.Capped("user")
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, capped)
+	capped, err = capper.Capped("other")
+	require.NoError(t, err)
+	require.Empty(t, capped)
+
+	// первый показ выходит из окна
+	current = current.Add(31 * time.Minute)
+	capped, err = capper.Capped("user")
+	require.NoError(t, err)
+	require.Empty(t, capped)
+}
+
+func TestCapperConcurrentSelect(t *testing.T) {
+	capper, err := NewCapper(NewMemoryStore(time.Hour), 3, time.Hour)
+	require.NoError(t, err)
+	errCapped := errors.New("capped")
+	choose := func(excluded []int) ([]int, error) {
+		if len(excluded) > 0 {
+			return nil, errCapped
+		}
+		return []int{1}, nil
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	selected := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if banners, err := capper.Select("user", choose); err == nil {
+				mu.Lock()
+				selected += len(banners)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 3, selected)
+	require.Empty(t, capper.(*capperImpl).users)
+
+	_, err = capper.Select("user", choose)
+	require.ErrorIs(t, err, errCapped)
+}
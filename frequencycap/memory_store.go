@@ -0,0 +1,74 @@
+package frequencycap
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryStore хранит показы в памяти процесса. Показы старше retention удаляются
+// периодической очисткой, которую запускает учет показа не чаще раза за retention,
+// а показы старше запрошенного окна - при чтении показов пользователя.
+type memoryStore struct {
+	mu        sync.Mutex
+	shows     map[string]map[int][]time.Time
+	retention time.Duration
+	swept     time.Time
+}
+
+// NewMemoryStore создает хранилище показов в памяти, хранящее показы не дольше retention.
+func NewMemoryStore(retention time.Duration) Store {
+	return &memoryStore{shows: make(map[string]map[int][]time.Time), retention: retention}
+}
+
+func (s *memoryStore) Add(userID string, bannerID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retention > 0 && at.Sub(s.swept) >= s.retention {
+		s.sweep(at.Add(-s.retention))
+		s.swept = at
+	}
+	banners, ok := s.shows[userID]
+	if !ok {
+		banners = make(map[int][]time.Time)
+		s.shows[userID] = banners
+	}
+	banners[bannerID] = append(banners[bannerID], at)
+	return nil
+}
+
+func (s *memoryStore) Counts(userID string, since time.Time) (map[int]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.trim(userID, since), nil
+}
+
+// sweep удаляет показы всех пользователей до момента since.
+func (s *memoryStore) sweep(since time.Time) {
+	for userID := range s.shows {
+		s.trim(userID, since)
+	}
+}
+
+// trim удаляет показы пользователя до момента since и возвращает число оставшихся показов баннеров.
+func (s *memoryStore) trim(userID string, since time.Time) map[int]int {
+	counts := make(map[int]int)
+	banners := s.shows[userID]
+	for bannerID, shows := range banners {
+		recent := shows[:0]
+		for _, at := range shows {
+			if !at.Before(since) {
+				recent = append(recent, at)
+			}
+		}
+		if len(recent) == 0 {
+			delete(banners, bannerID)
+			continue
+		}
+		banners[bannerID] = recent
+		counts[bannerID] = len(recent)
+	}
+	if len(banners) == 0 {
+		delete(s.shows, userID)
+	}
+	return counts
+}
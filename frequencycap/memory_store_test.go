@@ -0,0 +1,49 @@
+package frequencycap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore(time.Hour)
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.Add("user", 1, start))
+	require.NoError(t, s.Add("user", 1, start.Add(time.Hour)))
+	require.NoError(t, s.Add("user", 2, start))
+	require.NoError(t, s.Add("other", 1, start.Add(time.Hour)))
+
+	counts, err := s.Counts("user", start)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 2, 2: 1}, counts)
+
+	counts, err = s.Counts("user", start.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1}, counts)
+
+	// устаревшие показы удалены и не учитываются с более ранней границей
+	counts, err = s.Counts("user", start)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1}, counts)
+
+	counts, err = s.Counts("unknown", start)
+	require.NoError(t, err)
+	require.Empty(t, counts)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s := NewMemoryStore(time.Hour)
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, s.Add("user", 1, start))
+	require.NoError(t, s.Add("other", 1, start.Add(30*time.Minute)))
+	require.Len(t, s.(*memoryStore).shows, 2)
+
+	// показы пользователя, который больше не запрашивает баннеры, удаляются очисткой
+	require.NoError(t, s.Add("other", 1, start.Add(2*time.Hour)))
+	require.Len(t, s.(*memoryStore).shows, 1)
+	counts, err := s.Counts("other", start)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{1: 1}, counts)
+}
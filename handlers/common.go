@@ -1,15 +1,217 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
 	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/frequencycap"
+	"github.com/SergeyTyurin/banner-rotation/impression"
 	"github.com/SergeyTyurin/banner-rotation/messagebroker"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	// maxSelectCount - наибольшее число баннеров, выбираемых одним запросом
+	maxSelectCount = 100
+)
+
+var (
+	errIncorrectListQuery = errors.New("incorrect list query")
+	errIncorrectQuery     = errors.New("incorrect query parameters")
+	errIncorrectBody      = errors.New("incorrect request body")
+	errPageNotFound       = errors.New("page not found")
+	errMethodNotAllowed   = errors.New("method not allowed")
 )
 
+// errorCodes сопоставляет ошибкам код ответа и машиночитаемый код ошибки.
+// Остальные ошибки считаются внутренними.
+var errorCodes = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{errIncorrectQuery, http.StatusBadRequest, "incorrect_query"},
+	{errIncorrectBody, http.StatusBadRequest, "incorrect_body"},
+	{errIncorrectListQuery, http.StatusBadRequest, "incorrect_list_params"},
+	{database.ErrIncorrectListParams, http.StatusBadRequest, "incorrect_list_params"},
+	{database.ErrIncorrectEntity, http.StatusBadRequest, "incorrect_entity"},
+	{database.ErrIncorrectStrategy, http.StatusBadRequest, "incorrect_strategy"},
+	{database.ErrIncorrectGranularity, http.StatusBadRequest, "incorrect_granularity"},
+	{database.ErrIncorrectFlight, http.StatusBadRequest, "incorrect_flight"},
+	{database.ErrIncorrectPriority, http.StatusBadRequest, "incorrect_priority"},
+	{database.ErrIncorrectCount, http.StatusBadRequest, "incorrect_count"},
+	{database.ErrNotExist, http.StatusNotFound, "not_found"},
+	{frequencycap.ErrCapped, http.StatusNotFound, "frequency_capped"},
+	{database.ErrNotInRotation, http.StatusNotFound, "not_in_rotation"},
+	{database.ErrAlreadyInRotation, http.StatusConflict, "already_in_rotation"},
+	{database.ErrImpressionUsed, http.StatusConflict, "impression_used"},
+	{database.ErrBannerArchived, http.StatusConflict, "banner_archived"},
+	{impression.ErrInvalidToken, http.StatusForbidden, "invalid_impression"},
+	{impression.ErrExpiredToken, http.StatusGone, "expired_impression"},
+	{errPageNotFound, http.StatusNotFound, "not_found"},
+	{errMethodNotAllowed, http.StatusMethodNotAllowed, "method_not_allowed"},
+}
+
+const internalErrorCode = "internal_error"
+
+// errorResponse - тело ответа с ошибкой.
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handlers обрабатывает запросы сервиса. Без брокера события не отправляются,
+// без capper частота показов пользователю не ограничивается,
+// без impressions переходы засчитываются без подтверждения показа.
 type Handlers struct {
-	db     database.Database
-	broker messagebroker.MessageBroker
+	db          database.Database
+	broker      messagebroker.MessageBroker
+	capper      frequencycap.Capper
+	impressions impression.Signer
+}
+
+func NewHandlers(db database.Database, broker messagebroker.MessageBroker,
+	capper frequencycap.Capper, impressions impression.Signer,
+) Handlers {
+	return Handlers{db, broker, capper, impressions}
+}
+
+// listParams читает параметры списка limit, offset, sort и info.
+// Знак минус перед полем сортировки задает обратный порядок: sort=-info.
+func listParams(r *http.Request) (structures.ListParams, error) {
+	query := r.URL.Query()
+	params := structures.ListParams{Limit: defaultListLimit, Info: query.Get("info")}
+	var err error
+	if query.Has("limit") {
+		if params.Limit, err = strconv.Atoi(query.Get("limit")); err != nil {
+			return params, err
+		}
+		if params.Limit <= 0 || params.Limit > maxListLimit {
+			return params, errIncorrectListQuery
+		}
+	}
+	if query.Has("offset") {
+		if params.Offset, err = strconv.Atoi(query.Get("offset")); err != nil {
+			return params, err
+		}
+	}
+	params.Sort, params.Desc = strings.CutPrefix(query.Get("sort"), "-")
+	return params, nil
+}
+
+// mergeUpdate накладывает поля тела запроса на сохраненную сущность с идентификатором из тела.
+// Поля, отсутствующие в теле, сохраняют прежние значения, null сбрасывает необязательное поле.
+func mergeUpdate[T any](body []byte, get func(id int) (T, error)) (T, error) {
+	var entity T
+	var key struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &key); err != nil {
+		return entity, errIncorrectBody
+	}
+	stored, err := get(key.ID)
+	if err != nil {
+		return entity, err
+	}
+	// Копия через JSON не разделяет указатели с сущностью хранилища
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return entity, err
+	}
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return entity, err
+	}
+	if err := json.Unmarshal(body, &entity); err != nil {
+		return entity, errIncorrectBody
+	}
+	return entity, nil
+}
+
+// writeList отправляет список сущностей или ошибку его получения.
+func writeList[T any](w http.ResponseWriter, r *http.Request, list []T, err error) {
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// acceptsJSON сообщает, запросил ли клиент ответ в application/json.
+// Без такого запроса, в том числе без заголовка Accept, ответы сохраняют прежний формат:
+// ошибки и выбранный баннер отправляются текстом.
+func acceptsJSON(r *http.Request) bool {
+	textQuality, jsonQuality := 0.0, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if value, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		switch mediaType {
+		case "text/plain":
+			textQuality = math.Max(textQuality, quality)
+		case "application/json":
+			jsonQuality = math.Max(jsonQuality, quality)
+		}
+	}
+	return jsonQuality > textQuality
+}
+
+// writeJSON отправляет значение в JSON с кодом ответа status.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	resp, _ := json.Marshal(value)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(resp)
+}
+
+// writeText отправляет ответ в text/plain.
+func writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(text))
+}
+
+// NotFound отвечает на запрос неизвестного адреса так же, как на остальные ошибки.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, errPageNotFound)
+}
+
+// MethodNotAllowed отвечает на запрос с неподдерживаемым методом так же, как на остальные ошибки.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, errMethodNotAllowed)
 }
 
-func NewHandlers(db database.Database, broker messagebroker.MessageBroker) Handlers {
-	return Handlers{db, broker}
+// writeError отправляет ошибку текстом или, если клиент запросил application/json,
+// в виде {"error":{"code":...,"message":...}}.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := http.StatusInternalServerError, internalErrorCode
+	for _, item := range errorCodes {
+		if errors.Is(err, item.err) {
+			status, code = item.status, item.code
+			break
+		}
+	}
+	if acceptsJSON(r) {
+		writeJSON(w, status, errorResponse{errorBody{Code: code, Message: err.Error()}})
+		return
+	}
+	writeText(w, status, err.Error())
 }
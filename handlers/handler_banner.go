@@ -3,7 +3,6 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
 
@@ -13,99 +12,120 @@ import (
 
 func (h *Handlers) GetBanner(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	banner, err := h.db.DatabaseGetBanner(id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	resp, _ := json.Marshal(banner)
-	_, _ = w.Write(resp)
+	writeJSON(w, http.StatusOK, banner)
+}
+
+// GetBanners возвращает страницу списка баннеров.
+func (h *Handlers) GetBanners(w http.ResponseWriter, r *http.Request) {
+	params, err := listParams(r)
+	if err != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+	list, err := h.db.DatabaseGetBanners(params)
+	writeList(w, r, list, err)
 }
 
 func (h *Handlers) CreateBanner(w http.ResponseWriter, r *http.Request) {
 	requestBody := new(bytes.Buffer)
 	_, err := requestBody.ReadFrom(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
 	var banner structures.Banner
 	err = json.Unmarshal(requestBody.Bytes(), &banner)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
 
 	createdBanner, err := h.db.DatabaseCreateBanner(banner)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
-	resp, _ := json.Marshal(createdBanner)
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write(resp)
+	writeJSON(w, http.StatusCreated, createdBanner)
 }
 
 func (h *Handlers) UpdateBanner(w http.ResponseWriter, r *http.Request) {
 	requestBody := new(bytes.Buffer)
 	_, err := requestBody.ReadFrom(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
-	var banner structures.Banner
-	err = json.Unmarshal(requestBody.Bytes(), &banner)
+	var stored structures.Banner
+	banner, err := mergeUpdate(requestBody.Bytes(), func(id int) (structures.Banner, error) {
+		stored, err = h.db.DatabaseGetBanner(id)
+		return stored, err
+	})
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	err = h.db.DatabaseUpdateBanner(banner)
+	// Статус архивного баннера не меняется, приостановка и возобновление выполняются
+	// так же, как глобальные /rotation/pause и /rotation/resume
+	status := banner.Status
+	if status == "" {
+		status = stored.Status
+	}
+	if stored.Status == structures.BannerArchived && status != structures.BannerArchived {
+		writeError(w, r, database.ErrBannerArchived)
+		return
+	}
+	if status == structures.BannerActive || status == structures.BannerPaused {
+		banner.Status = stored.Status
+	}
+	if err := h.db.DatabaseUpdateBanner(banner); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if status == stored.Status {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	switch status {
+	case structures.BannerActive:
+		err = h.db.DatabaseResumeBanner(banner.ID, 0)
+	case structures.BannerPaused:
+		err = h.db.DatabasePauseBanner(banner.ID, 0)
+	}
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
+	h.sendBannerStatusEvent(banner.ID, 0, status)
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *Handlers) DeleteBanner(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	err = h.db.DatabaseDeleteBanner(id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
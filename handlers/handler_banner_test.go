@@ -11,19 +11,20 @@ import (
 
 	"github.com/SergeyTyurin/banner-rotation/configs"
 	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/messagebroker"
 	"github.com/SergeyTyurin/banner-rotation/structures"
 	"github.com/stretchr/testify/require"
 )
 
 func TestBadRequestBanner(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/banner")
 
 	t.Run("create", func(t *testing.T) {
@@ -70,14 +71,14 @@ func TestBadRequestBanner(t *testing.T) {
 }
 
 func TestNonExistsBanner(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/banner")
 
 	t.Run("update", func(t *testing.T) {
@@ -114,3 +115,172 @@ func TestNonExistsBanner(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, response.Code)
 	})
 }
+
+func TestGetBanners(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	for _, info := range []string{"first", "second", "third"} {
+		_, _ = d.DatabaseCreateBanner(structures.Banner{Info: info})
+	}
+	h := Handlers{d, nil, nil, nil}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/banner?"+query, nil)
+		response := httptest.NewRecorder()
+		h.GetBanners(response, request)
+		return response
+	}
+
+	t.Run("page", func(t *testing.T) {
+		response := get("limit=2&offset=1&sort=-info")
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, "application/json", response.Header().Get("Content-Type"))
+		var banners []structures.Banner
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &banners))
+		require.Len(t, banners, 2)
+		require.Equal(t, "second", banners[0].Info)
+		require.Equal(t, "first", banners[1].Info)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		response := get("info=IR")
+		var banners []structures.Banner
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &banners))
+		require.Len(t, banners, 2)
+		require.Equal(t, 1, banners[0].ID)
+		require.Equal(t, 3, banners[1].ID)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		for _, query := range []string{"limit=0", "limit=bad", "limit=100000", "offset=-1", "sort=unknown"} {
+			require.Equal(t, http.StatusBadRequest, get(query).Code, query)
+		}
+	})
+}
+
+func TestBannerFields(t *testing.T) {
+	h := Handlers{database.NewMemoryDatabase(), nil, nil, nil}
+
+	create := func(body string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/banner",
+			bytes.NewReader([]byte(body)))
+		response := httptest.NewRecorder()
+		h.CreateBanner(response, request)
+		return response
+	}
+
+	response := create(`{"info":"sale","target_url":"https://example.com","creative_url":"https://cdn.example.com/1.png",
+		"width":300,"height":250,"status":"paused"}`)
+	require.Equal(t, http.StatusCreated, response.Code)
+	var created structures.Banner
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &created))
+	require.Equal(t, "https://example.com", created.TargetURL)
+	require.Equal(t, 300, created.Width)
+	require.Equal(t, structures.BannerPaused, created.Status)
+	require.False(t, created.CreatedAt.IsZero())
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &fields))
+	require.Contains(t, fields, "creative_url")
+	require.Contains(t, fields, "updated_at")
+
+	require.Equal(t, http.StatusBadRequest, create(`{"info":"sale","status":"unknown"}`).Code)
+	require.Equal(t, http.StatusBadRequest, create(`{"info":"sale","target_url":"not a url"}`).Code)
+}
+
+func TestUpdateBannerPartial(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{
+		Info:      "sale",
+		TargetURL: "https://example.com",
+		Width:     300,
+		Height:    250,
+	})
+	other, _ := d.DatabaseCreateBanner(structures.Banner{Info: "other"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	require.NoError(t, d.DatabaseAddToRotation(banner.ID, slot.ID))
+	require.NoError(t, d.DatabaseAddToRotation(other.ID, slot.ID))
+	require.NoError(t, d.DatabasePauseBanner(banner.ID, 0))
+
+	body := fmt.Sprintf(`{"id": %d, "info": "updated"}`, banner.ID)
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodPut, "/banner",
+		bytes.NewReader([]byte(body)))
+	response := httptest.NewRecorder()
+	h.UpdateBanner(response, request)
+	require.Equal(t, http.StatusOK, response.Code)
+
+	updated, err := d.DatabaseGetBanner(banner.ID)
+	require.NoError(t, err)
+	require.Equal(t, "updated", updated.Info)
+	require.Equal(t, "https://example.com", updated.TargetURL)
+	require.Equal(t, 300, updated.Width)
+	require.Equal(t, 250, updated.Height)
+	require.Equal(t, structures.BannerPaused, updated.Status)
+
+	// Обновление без статуса в обход обработчика также сохраняет приостановку
+	require.NoError(t, d.DatabaseUpdateBanner(structures.Banner{ID: banner.ID, Info: "direct"}))
+	updated, _ = d.DatabaseGetBanner(banner.ID)
+	require.Equal(t, structures.BannerPaused, updated.Status)
+
+	for i := 0; i < 10; i++ {
+		bannerID, err := database.SelectFromRotation(d, slot.ID, group.ID)
+		require.NoError(t, err)
+		require.Equal(t, other.ID, bannerID)
+	}
+}
+
+// statusBroker запоминает события изменения статуса баннера.
+type statusBroker struct {
+	messagebroker.MessageBroker
+	events []string
+}
+
+func (b *statusBroker) SendBannerStatusEvent(msg string) error {
+	b.events = append(b.events, msg)
+	return nil
+}
+
+func TestUpdateBannerStatus(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	broker := &statusBroker{}
+	h := Handlers{d, broker, nil, nil}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	archived, _ := d.DatabaseCreateBanner(structures.Banner{Info: "archived", Status: structures.BannerArchived})
+
+	update := func(body string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPut, "/banner",
+			bytes.NewReader([]byte(body)))
+		response := httptest.NewRecorder()
+		h.UpdateBanner(response, request)
+		return response
+	}
+
+	// архивный баннер не возвращается в показы
+	for _, status := range []string{structures.BannerActive, structures.BannerPaused} {
+		response := update(fmt.Sprintf(`{"id": %d, "info": "restored", "status": %q}`, archived.ID, status))
+		require.Equal(t, http.StatusConflict, response.Code)
+		require.Equal(t, database.ErrBannerArchived.Error(), response.Body.String())
+	}
+	stored, _ := d.DatabaseGetBanner(archived.ID)
+	require.Equal(t, structures.BannerArchived, stored.Status)
+	require.Equal(t, "archived", stored.Info)
+	require.Equal(t, http.StatusOK, update(fmt.Sprintf(`{"id": %d, "info": "updated"}`, archived.ID)).Code)
+
+	// изменение статуса отправляет то же событие, что и глобальная приостановка
+	response := update(fmt.Sprintf(`{"id": %d, "status": "paused"}`, banner.ID))
+	require.Equal(t, http.StatusOK, response.Code)
+	stored, _ = d.DatabaseGetBanner(banner.ID)
+	require.Equal(t, structures.BannerPaused, stored.Status)
+	require.Equal(t, http.StatusOK, update(fmt.Sprintf(`{"id": %d, "info": "paused"}`, banner.ID)).Code)
+	require.Equal(t, http.StatusBadRequest, update(fmt.Sprintf(`{"id": %d, "status": "unknown"}`, banner.ID)).Code)
+	require.Equal(t, http.StatusOK, update(fmt.Sprintf(`{"id": %d, "status": "archived"}`, banner.ID)).Code)
+	require.Equal(t, []string{
+		fmt.Sprintf("banner_id=%d, slot_id=0, status=paused", banner.ID),
+		fmt.Sprintf("banner_id=%d, slot_id=0, status=archived", banner.ID),
+	}, broker.events)
+
+	// обновление в обход обработчика также не меняет статус архивного баннера
+	err := d.DatabaseUpdateBanner(structures.Banner{ID: banner.ID, Status: structures.BannerActive})
+	require.ErrorIs(t, err, database.ErrBannerArchived)
+}
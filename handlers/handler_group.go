@@ -3,86 +3,79 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/SergeyTyurin/banner-rotation/database"
 	"github.com/SergeyTyurin/banner-rotation/structures"
 )
 
 func (h *Handlers) GetGroup(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	group, err := h.db.DatabaseGetGroup(id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	resp, _ := json.Marshal(group)
-	_, _ = w.Write(resp)
+	writeJSON(w, http.StatusOK, group)
+}
+
+// GetGroups возвращает страницу списка групп.
+func (h *Handlers) GetGroups(w http.ResponseWriter, r *http.Request) {
+	params, err := listParams(r)
+	if err != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+	list, err := h.db.DatabaseGetGroups(params)
+	writeList(w, r, list, err)
 }
 
 func (h *Handlers) CreateGroup(w http.ResponseWriter, r *http.Request) {
 	requestBody := new(bytes.Buffer)
 	_, err := requestBody.ReadFrom(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
 	var group structures.Group
 	err = json.Unmarshal(requestBody.Bytes(), &group)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
 
 	createdGroup, err := h.db.DatabaseCreateGroup(group)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
-	resp, _ := json.Marshal(createdGroup)
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write(resp)
+	writeJSON(w, http.StatusCreated, createdGroup)
 }
 
 func (h *Handlers) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 	requestBody := new(bytes.Buffer)
 	_, err := requestBody.ReadFrom(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
-	var group structures.Group
-	err = json.Unmarshal(requestBody.Bytes(), &group)
+	group, err := mergeUpdate(requestBody.Bytes(), h.db.DatabaseGetGroup)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	err = h.db.DatabaseUpdateGroup(group)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -90,22 +83,17 @@ func (h *Handlers) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	err = h.db.DatabaseDeleteGroup(id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -16,14 +16,14 @@ import (
 )
 
 func TestBadRequestGroup(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/group")
 
 	t.Run("create", func(t *testing.T) {
@@ -70,14 +70,14 @@ func TestBadRequestGroup(t *testing.T) {
 }
 
 func TestNonExistsGroup(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/group")
 
 	t.Run("update", func(t *testing.T) {
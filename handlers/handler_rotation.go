@@ -1,87 +1,217 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/frequencycap"
+	"github.com/SergeyTyurin/banner-rotation/impression"
+	"github.com/SergeyTyurin/banner-rotation/structures"
 )
 
 func (h *Handlers) HandlerAddToRotation(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("slot_id") || !r.URL.Query().Has("banner_id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 
 	slotID, slotErr := strconv.Atoi(r.URL.Query().Get("slot_id"))
 	bannerID, bannerErr := strconv.Atoi(r.URL.Query().Get("banner_id"))
 	if slotErr != nil || bannerErr != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
-	err := h.db.DatabaseAddToRotation(bannerID, slotID)
-	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
+	// По умолчанию повторное добавление не считается ошибкой,
+	// с strict=true на него возвращается 409 Conflict
+	strict := false
+	if r.URL.Query().Has("strict") {
+		var strictErr error
+		if strict, strictErr = strconv.ParseBool(r.URL.Query().Get("strict")); strictErr != nil {
+			writeError(w, r, errIncorrectQuery)
+			return
 		}
-		_, _ = w.Write([]byte(err.Error()))
+	}
+	settings, err := parseRotationSettings(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Новый баннер добавляется сразу с параметрами, у баннера в ротации меняются только переданные
+	err = h.db.DatabaseAddToRotationWith(bannerID, slotID, settings.flight, settings.priority)
+	alreadyInRotation := errors.Is(err, database.ErrAlreadyInRotation) && !strict
+	if alreadyInRotation {
+		err = h.setRotationSettings(bannerID, slotID, settings)
+	}
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	// Ответ сообщает, был ли баннер уже в ротации: текстом ошибки или полем already_in_rotation
+	switch {
+	case acceptsJSON(r):
+		writeJSON(w, http.StatusOK, addToRotationResponse{AlreadyInRotation: alreadyInRotation})
+	case alreadyInRotation:
+		writeText(w, http.StatusOK, database.ErrAlreadyInRotation.Error())
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// addToRotationResponse - ответ на добавление баннера в ротацию в JSON.
+type addToRotationResponse struct {
+	AlreadyInRotation bool `json:"already_in_rotation"`
+}
+
+// rotationSettings - необязательные параметры баннера в ротации слота.
+type rotationSettings struct {
+	flight      structures.Flight
+	hasFlight   bool
+	priority    structures.Priority
+	hasPriority bool
+}
+
+// parseRotationSettings разбирает период показов start_at/end_at в формате RFC 3339,
+// вес weight и гарантированную долю показов share.
+func parseRotationSettings(r *http.Request) (rotationSettings, error) {
+	var settings rotationSettings
+	var err error
+	if settings.flight, settings.hasFlight, err = rotationFlight(r); err != nil {
+		return settings, err
+	}
+	settings.priority, settings.hasPriority, err = rotationPriority(r)
+	return settings, err
+}
+
+func (h *Handlers) setRotationSettings(bannerID, slotID int, settings rotationSettings) error {
+	if settings.hasPriority {
+		if err := h.db.DatabaseSetRotationPriority(bannerID, slotID, settings.priority); err != nil {
+			return err
+		}
+	}
+	if settings.hasFlight {
+		return h.db.DatabaseSetRotationFlight(bannerID, slotID, settings.flight)
+	}
+	return nil
+}
+
+// rotationPriority разбирает приоритет баннера из параметров запроса.
+// Параметры задаются вместе: отсутствующий вес равен 1, отсутствующая доля - 0.
+func rotationPriority(r *http.Request) (structures.Priority, bool, error) {
+	priority := structures.Priority{Weight: 1}
+	query := r.URL.Query()
+	if !query.Has("weight") && !query.Has("share") {
+		return priority, false, nil
+	}
+	var weightErr, shareErr error
+	if query.Has("weight") {
+		priority.Weight, weightErr = strconv.ParseFloat(query.Get("weight"), 64)
+	}
+	if query.Has("share") {
+		priority.Share, shareErr = strconv.ParseFloat(query.Get("share"), 64)
+	}
+	if weightErr != nil || shareErr != nil {
+		return priority, false, database.ErrIncorrectPriority
+	}
+	return priority, true, nil
+}
+
+// rotationFlight разбирает период показов из параметров запроса.
+// Заданный период заменяет прежний целиком: отсутствующая или пустая граница период не ограничивает,
+// поэтому запрос с пустым start_at или end_at снимает ограничение периода.
+// Период проверяется до добавления в ротацию, чтобы некорректный запрос не менял состав ротации.
+func rotationFlight(r *http.Request) (structures.Flight, bool, error) {
+	var flight structures.Flight
+	query := r.URL.Query()
+	if !query.Has("start_at") && !query.Has("end_at") {
+		return flight, false, nil
+	}
+	startAt, startErr := flightBound(query.Get("start_at"))
+	endAt, endErr := flightBound(query.Get("end_at"))
+	if startErr != nil || endErr != nil {
+		return flight, false, database.ErrIncorrectFlight
+	}
+	flight.StartAt, flight.EndAt = startAt, endAt
+	if startAt != nil && endAt != nil && !endAt.After(*startAt) {
+		return flight, false, database.ErrIncorrectFlight
+	}
+	return flight, true, nil
+}
+
+// flightBound разбирает границу периода показов, пустое значение означает отсутствие границы.
+func flightBound(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	bound, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &bound, nil
 }
 
 func (h *Handlers) DeleteFromRotation(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("slot_id") || !r.URL.Query().Has("banner_id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 
 	slotID, slotErr := strconv.Atoi(r.URL.Query().Get("slot_id"))
 	bannerID, bannerErr := strconv.Atoi(r.URL.Query().Get("banner_id"))
 	if slotErr != nil || bannerErr != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	err := h.db.DatabaseDeleteFromRotation(bannerID, slotID)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// RegisterTransition засчитывает переход по баннеру. С подписью показов переход принимается
+// только по токену impression_id, выданному при выборе баннера, и только один раз.
 func (h *Handlers) RegisterTransition(w http.ResponseWriter, r *http.Request) {
-	if !r.URL.Query().Has("slot_id") || !r.URL.Query().Has("group_id") || !r.URL.Query().Has("banner_id") {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	var slotID, groupID, bannerID int
+	var shown impression.Impression
+	if h.impressions != nil {
+		var err error
+		if shown, err = h.verifyImpression(r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		slotID, groupID, bannerID = shown.SlotID, shown.GroupID, shown.BannerID
+	} else {
+		if !r.URL.Query().Has("slot_id") || !r.URL.Query().Has("group_id") || !r.URL.Query().Has("banner_id") {
+			writeError(w, r, errIncorrectQuery)
+			return
+		}
+		var groupErr, slotErr, bannerErr error
+		groupID, groupErr = strconv.Atoi(r.URL.Query().Get("group_id"))
+		slotID, slotErr = strconv.Atoi(r.URL.Query().Get("slot_id"))
+		bannerID, bannerErr = strconv.Atoi(r.URL.Query().Get("banner_id"))
+		if groupErr != nil || slotErr != nil || bannerErr != nil {
+			writeError(w, r, errIncorrectQuery)
+			return
+		}
 	}
 
-	groupID, groupErr := strconv.Atoi(r.URL.Query().Get("group_id"))
-	slotID, slotErr := strconv.Atoi(r.URL.Query().Get("slot_id"))
-	bannerID, bannerErr := strconv.Atoi(r.URL.Query().Get("banner_id"))
-	if groupErr != nil || slotErr != nil || bannerErr != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	var err error
+	if h.impressions != nil {
+		// Показ отмечается использованным вместе с учетом перехода
+		err = h.db.DatabaseRegisterImpressionTransition(shown.ID, shown.ExpiresAt, slotID, bannerID, groupID)
+	} else {
+		err = h.db.DatabaseRegisterTransition(slotID, bannerID, groupID)
 	}
-
-	err := h.db.DatabaseRegisterTransition(slotID, bannerID, groupID)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) || errors.Is(err, database.ErrNotInRotation) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	if h.broker != nil {
@@ -91,33 +221,213 @@ func (h *Handlers) RegisterTransition(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// verifyImpression проверяет токен показа impression_id.
+// Заданные вместе с токеном slot_id, group_id и banner_id должны совпадать с показом.
+func (h *Handlers) verifyImpression(r *http.Request) (impression.Impression, error) {
+	if !r.URL.Query().Has("impression_id") {
+		return impression.Impression{}, errIncorrectQuery
+	}
+	shown, err := h.impressions.Verify(r.URL.Query().Get("impression_id"))
+	if err != nil {
+		return shown, err
+	}
+	for name, expected := range map[string]int{
+		"slot_id": shown.SlotID, "group_id": shown.GroupID, "banner_id": shown.BannerID,
+	} {
+		id, err := optionalID(r, name)
+		if err != nil || (r.URL.Query().Has(name) && id != expected) {
+			return shown, errIncorrectQuery
+		}
+	}
+	return shown, nil
+}
+
+// impressionHeader - заголовок ответа с токеном показа для клиентов, получающих ответ текстом.
+const impressionHeader = "X-Impression-Id"
+
 func (h *Handlers) SelectFromRotation(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("slot_id") || !r.URL.Query().Has("group_id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 
 	groupID, groupErr := strconv.Atoi(r.URL.Query().Get("group_id"))
 	slotID, slotErr := strconv.Atoi(r.URL.Query().Get("slot_id"))
 	if groupErr != nil || slotErr != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 
-	bannerID, err := h.db.DatabaseSelectFromRotation(slotID, groupID)
+	// С параметром count выбирается до count разных баннеров, ответ - массив
+	count := 1
+	if r.URL.Query().Has("count") {
+		var countErr error
+		count, countErr = strconv.Atoi(r.URL.Query().Get("count"))
+		if countErr != nil || count < 1 || count > maxSelectCount {
+			writeError(w, r, database.ErrIncorrectCount)
+			return
+		}
+	}
+
+	var selected structures.SelectedBanners
+	choose := func(excluded []int) ([]int, error) {
+		var err error
+		selected, err = h.db.DatabaseSelectBanners(slotID, groupID, count, excluded)
+		if err != nil {
+			return nil, err
+		}
+		banners := make([]int, 0, len(selected.Banners))
+		for _, banner := range selected.Banners {
+			banners = append(banners, banner.ID)
+		}
+		return banners, nil
+	}
+	// Для пользователя user_id не выбираются баннеры, достигшие ограничения частоты показов
+	var banners []int
+	var err error
+	if userID := r.URL.Query().Get("user_id"); h.capper != nil && userID != "" {
+		banners, err = h.capper.Select(userID, choose)
+		if errors.Is(err, database.ErrAllExcluded) {
+			err = frequencycap.ErrCapped
+		}
+	} else {
+		banners, err = choose(nil)
+	}
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
+		// ErrNotInRotation - в слоте нет активных баннеров
+		writeError(w, r, err)
+		return
+	}
+	for _, bannerID := range banners {
+		if h.broker != nil {
+			msg := fmt.Sprintf("slot_id=%d, group_id=%d, banner_id=%d", slotID, groupID, bannerID)
+			_ = h.broker.SendSelectFromRotationEvent(msg)
 		}
-		_, _ = w.Write([]byte(err.Error()))
+	}
+	// Без запроса application/json ответ сохраняет прежний формат: идентификатор баннера текстом,
+	// а с count - JSON-массив идентификаторов. Токены показов передаются заголовками X-Impression-Id
+	// в порядке баннеров
+	if !acceptsJSON(r) {
+		if h.impressions != nil {
+			for _, bannerID := range banners {
+				token, err := h.impressions.Issue(slotID, groupID, bannerID)
+				if err != nil {
+					writeError(w, r, err)
+					return
+				}
+				w.Header().Add(impressionHeader, token)
+			}
+		}
+		if !r.URL.Query().Has("count") {
+			writeText(w, http.StatusOK, strconv.Itoa(banners[0]))
+			return
+		}
+		writeJSON(w, http.StatusOK, banners)
 		return
 	}
-	if h.broker != nil {
-		msg := fmt.Sprintf("slot_id=%d, group_id=%d, banner_id=%d", slotID, groupID, bannerID)
-		_ = h.broker.SendSelectFromRotationEvent(msg)
+	selections, err := h.selections(slotID, groupID, selected)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !r.URL.Query().Has("count") {
+		writeJSON(w, http.StatusOK, selections[0])
+		return
+	}
+	writeJSON(w, http.StatusOK, selections)
+}
+
+// selections описывает выбранные баннеры для ответа в JSON.
+func (h *Handlers) selections(slotID, groupID int, selected structures.SelectedBanners,
+) ([]structures.Selection, error) {
+	selections := make([]structures.Selection, 0, len(selected.Banners))
+	for _, banner := range selected.Banners {
+		impressionID, err := h.impressionID(slotID, groupID, banner.ID)
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, structures.Selection{
+			Banner:       banner,
+			SlotID:       slotID,
+			GroupID:      groupID,
+			ImpressionID: impressionID,
+			Algorithm:    selected.Algorithm,
+		})
+	}
+	return selections, nil
+}
+
+// impressionID возвращает подписанный токен показа, а без подписи показов - случайный идентификатор.
+func (h *Handlers) impressionID(slotID, groupID, bannerID int) (string, error) {
+	if h.impressions != nil {
+		return h.impressions.Issue(slotID, groupID, bannerID)
+	}
+	return newImpressionID()
+}
+
+// newImpressionID возвращает случайный идентификатор показа.
+func newImpressionID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// setBannerStatus приостанавливает или возобновляет показы баннера banner_id
+// в слоте slot_id, а без slot_id - во всех слотах.
+func (h *Handlers) setBannerStatus(w http.ResponseWriter, r *http.Request,
+	status string, update func(bannerID, slotID int) error,
+) {
+	if !r.URL.Query().Has("banner_id") {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+	bannerID, bannerErr := strconv.Atoi(r.URL.Query().Get("banner_id"))
+	slotID, slotErr := optionalID(r, "slot_id")
+	if bannerErr != nil || slotErr != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+
+	if err := update(bannerID, slotID); err != nil {
+		writeError(w, r, err)
+		return
 	}
+	h.sendBannerStatusEvent(bannerID, slotID, status)
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte(strconv.Itoa(bannerID)))
+}
+
+// sendBannerStatusEvent сообщает об изменении статуса баннера в слоте slotID, нулевой слот - во всех слотах.
+func (h *Handlers) sendBannerStatusEvent(bannerID, slotID int, status string) {
+	if h.broker != nil {
+		msg := fmt.Sprintf("banner_id=%d, slot_id=%d, status=%s", bannerID, slotID, status)
+		_ = h.broker.SendBannerStatusEvent(msg)
+	}
+}
+
+func (h *Handlers) PauseBanner(w http.ResponseWriter, r *http.Request) {
+	h.setBannerStatus(w, r, structures.BannerPaused, h.db.DatabasePauseBanner)
+}
+
+func (h *Handlers) ResumeBanner(w http.ResponseWriter, r *http.Request) {
+	h.setBannerStatus(w, r, structures.BannerActive, h.db.DatabaseResumeBanner)
+}
+
+// GetRotationMembers возвращает баннеры в ротации слота slot_id или слоты,
+// в ротации которых участвует баннер banner_id.
+func (h *Handlers) GetRotationMembers(w http.ResponseWriter, r *http.Request) {
+	slotID, slotErr := optionalID(r, "slot_id")
+	bannerID, bannerErr := optionalID(r, "banner_id")
+	if slotErr != nil || bannerErr != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+
+	members, err := h.db.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: slotID, BannerID: bannerID})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
 }
@@ -2,25 +2,33 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/SergeyTyurin/banner-rotation/bannerselector"
 	"github.com/SergeyTyurin/banner-rotation/configs"
 	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/frequencycap"
+	"github.com/SergeyTyurin/banner-rotation/impression"
+	"github.com/SergeyTyurin/banner-rotation/structures"
 	"github.com/stretchr/testify/require"
 )
 
 func TestBadRequestRotaton(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/rotation")
 
 	t.Run("add", func(t *testing.T) {
@@ -74,14 +82,14 @@ func TestBadRequestRotaton(t *testing.T) {
 }
 
 func TestNonExistsRotaton(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/rotation")
 
 	t.Run("add", func(t *testing.T) {
@@ -133,3 +141,528 @@ func TestNonExistsRotaton(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, response.Code)
 	})
 }
+
+func TestRotationFlow(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	url := "http://127.0.0.1/rotation"
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+
+	do := func(handler http.HandlerFunc, method string, params map[string]int) *httptest.ResponseRecorder {
+		r, _ := http.NewRequestWithContext(context.Background(), method, url, nil)
+		q := r.URL.Query()
+		for key, value := range params {
+			q.Add(key, strconv.Itoa(value))
+		}
+		r.URL.RawQuery = q.Encode()
+		response := httptest.NewRecorder()
+		handler(response, r)
+		return response
+	}
+
+	response := do(h.HandlerAddToRotation, http.MethodPost, map[string]int{"banner_id": banner.ID, "slot_id": slot.ID})
+	require.Equal(t, http.StatusOK, response.Code)
+
+	// повторное добавление идемпотентно, а в строгом режиме возвращает конфликт
+	response = do(h.HandlerAddToRotation, http.MethodPost, map[string]int{"banner_id": banner.ID, "slot_id": slot.ID})
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, database.ErrAlreadyInRotation.Error(), response.Body.String())
+	response = do(h.HandlerAddToRotation, http.MethodPost,
+		map[string]int{"banner_id": banner.ID, "slot_id": slot.ID, "strict": 1})
+	require.Equal(t, http.StatusConflict, response.Code)
+
+	response = do(h.SelectFromRotation, http.MethodGet, map[string]int{"slot_id": slot.ID, "group_id": group.ID})
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, strconv.Itoa(banner.ID), response.Body.String())
+
+	response = do(h.RegisterTransition, http.MethodPut,
+		map[string]int{"slot_id": slot.ID, "group_id": group.ID, "banner_id": banner.ID})
+	require.Equal(t, http.StatusOK, response.Code)
+
+	response = do(h.DeleteFromRotation, http.MethodDelete, map[string]int{"banner_id": banner.ID, "slot_id": slot.ID})
+	require.Equal(t, http.StatusOK, response.Code)
+
+	response = do(h.RegisterTransition, http.MethodPut,
+		map[string]int{"slot_id": slot.ID, "group_id": group.ID, "banner_id": banner.ID})
+	require.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestGetRotationMembers(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+	_, _ = database.SelectFromRotation(d, slot.ID, group.ID)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/rotation/members?"+query, nil)
+		response := httptest.NewRecorder()
+		h.GetRotationMembers(response, request)
+		return response
+	}
+
+	response := get(fmt.Sprintf("slot_id=%d", slot.ID))
+	require.Equal(t, http.StatusOK, response.Code)
+	var members []structures.RotationMember
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &members))
+	require.Len(t, members, 1)
+	require.Equal(t, banner.ID, members[0].Banner.ID)
+	require.Len(t, members[0].Statistics, 1)
+	require.Equal(t, 1, members[0].Statistics[0].Displays)
+
+	response = get("banner_id=100")
+	require.Equal(t, http.StatusOK, response.Code)
+	require.JSONEq(t, "[]", response.Body.String())
+
+	require.Equal(t, http.StatusBadRequest, get("slot_id=bad").Code)
+}
+
+func TestPauseBanner(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	first, _ := d.DatabaseCreateBanner(structures.Banner{Info: "first"})
+	second, _ := d.DatabaseCreateBanner(structures.Banner{Info: "second"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(first.ID, slot.ID)
+	_ = d.DatabaseAddToRotation(second.ID, slot.ID)
+
+	do := func(handler http.HandlerFunc, query string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/rotation/pause?"+query, nil)
+		response := httptest.NewRecorder()
+		handler(response, request)
+		return response
+	}
+	selectQuery := fmt.Sprintf("slot_id=%d&group_id=%d", slot.ID, group.ID)
+
+	response := do(h.PauseBanner, fmt.Sprintf("banner_id=%d&slot_id=%d", first.ID, slot.ID))
+	require.Equal(t, http.StatusOK, response.Code)
+	response = do(h.SelectFromRotation, selectQuery)
+	require.Equal(t, strconv.Itoa(second.ID), response.Body.String())
+
+	response = do(h.PauseBanner, fmt.Sprintf("banner_id=%d", second.ID))
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, http.StatusNotFound, do(h.SelectFromRotation, selectQuery).Code)
+
+	response = do(h.ResumeBanner, fmt.Sprintf("banner_id=%d&slot_id=%d", first.ID, slot.ID))
+	require.Equal(t, http.StatusOK, response.Code)
+	response = do(h.SelectFromRotation, selectQuery)
+	require.Equal(t, strconv.Itoa(first.ID), response.Body.String())
+
+	require.Equal(t, http.StatusBadRequest, do(h.PauseBanner, "").Code)
+	require.Equal(t, http.StatusBadRequest, do(h.PauseBanner, "banner_id=1&slot_id=bad").Code)
+	require.Equal(t, http.StatusNotFound, do(h.ResumeBanner, "banner_id=100").Code)
+	require.Equal(t, http.StatusNotFound, do(h.PauseBanner, fmt.Sprintf("banner_id=%d&slot_id=100", first.ID)).Code)
+
+	archived, _ := d.DatabaseCreateBanner(structures.Banner{Status: structures.BannerArchived})
+	response = do(h.ResumeBanner, fmt.Sprintf("banner_id=%d", archived.ID))
+	require.Equal(t, http.StatusConflict, response.Code)
+	require.Equal(t, database.ErrBannerArchived.Error(), response.Body.String())
+}
+
+func TestRotationFlight(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+
+	do := func(handler http.HandlerFunc, method string, query url.Values) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), method, "/rotation?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+		handler(response, request)
+		return response
+	}
+	add := url.Values{"banner_id": {strconv.Itoa(banner.ID)}, "slot_id": {strconv.Itoa(slot.ID)}}
+	selectQuery := url.Values{"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}}
+
+	// некорректный период не добавляет баннер в ротацию
+	add.Set("start_at", time.Now().Add(time.Hour).Format(time.RFC3339))
+	add.Set("end_at", time.Now().Format(time.RFC3339))
+	require.Equal(t, http.StatusBadRequest, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+	require.Equal(t, http.StatusNotFound, do(h.SelectFromRotation, http.MethodGet, selectQuery).Code)
+	add.Set("end_at", "tomorrow")
+	require.Equal(t, http.StatusBadRequest, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+
+	add.Set("end_at", time.Now().Add(2*time.Hour).Format(time.RFC3339))
+	require.Equal(t, http.StatusOK, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+	require.Equal(t, http.StatusNotFound, do(h.SelectFromRotation, http.MethodGet, selectQuery).Code)
+
+	// повторное добавление изменяет период показов
+	add.Set("start_at", time.Now().Add(-time.Hour).Format(time.RFC3339))
+	require.Equal(t, http.StatusOK, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+	response := do(h.SelectFromRotation, http.MethodGet, selectQuery)
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, strconv.Itoa(banner.ID), response.Body.String())
+
+	// повторное добавление без периода сохраняет его, пустая граница снимает ограничение
+	add.Set("start_at", time.Now().Add(time.Hour).Format(time.RFC3339))
+	require.Equal(t, http.StatusOK, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+	add.Del("start_at")
+	add.Del("end_at")
+	require.Equal(t, http.StatusOK, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+	require.Equal(t, http.StatusNotFound, do(h.SelectFromRotation, http.MethodGet, selectQuery).Code)
+	add.Set("start_at", "")
+	require.Equal(t, http.StatusOK, do(h.HandlerAddToRotation, http.MethodPost, add).Code)
+	require.Equal(t, http.StatusOK, do(h.SelectFromRotation, http.MethodGet, selectQuery).Code)
+	members, err := d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: slot.ID})
+	require.NoError(t, err)
+	require.Nil(t, members[0].StartAt)
+	require.Nil(t, members[0].EndAt)
+}
+
+func TestRotationPriority(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	first, _ := d.DatabaseCreateBanner(structures.Banner{Info: "first"})
+	second, _ := d.DatabaseCreateBanner(structures.Banner{Info: "second"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+
+	add := func(bannerID int, query url.Values) *httptest.ResponseRecorder {
+		query.Set("banner_id", strconv.Itoa(bannerID))
+		query.Set("slot_id", strconv.Itoa(slot.ID))
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/rotation?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+		h.HandlerAddToRotation(response, request)
+		return response
+	}
+
+	require.Equal(t, http.StatusOK, add(first.ID, url.Values{"weight": {"2"}, "share": {"0.7"}}).Code)
+	members, _ := d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: slot.ID})
+	require.Equal(t, structures.Priority{Weight: 2, Share: 0.7}, members[0].Priority)
+
+	// сумма долей превышает 1, баннер не добавляется в ротацию
+	require.Equal(t, http.StatusBadRequest, add(second.ID, url.Values{"share": {"0.5"}}).Code)
+	members, _ = d.DatabaseGetRotationMembers(structures.RotationFilter{SlotID: slot.ID})
+	require.Len(t, members, 1)
+
+	require.Equal(t, http.StatusBadRequest, add(second.ID, url.Values{"weight": {"heavy"}}).Code)
+	require.Equal(t, http.StatusBadRequest, add(second.ID, url.Values{"weight": {"0"}}).Code)
+	require.Equal(t, http.StatusOK, add(second.ID, url.Values{"share": {"0.3"}}).Code)
+
+	// повторное добавление изменяет приоритет
+	require.Equal(t, http.StatusOK, add(first.ID, url.Values{"weight": {"1"}}).Code)
+	members, _ = d.DatabaseGetRotationMembers(structures.RotationFilter{BannerID: first.ID})
+	require.Equal(t, structures.Priority{Weight: 1}, members[0].Priority)
+}
+
+func TestFrequencyCap(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	capper, _ := frequencycap.NewCapper(frequencycap.NewMemoryStore(time.Hour), 2, time.Hour)
+	h := Handlers{d, nil, capper, nil}
+	first, _ := d.DatabaseCreateBanner(structures.Banner{Info: "first"})
+	second, _ := d.DatabaseCreateBanner(structures.Banner{Info: "second"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(first.ID, slot.ID)
+	_ = d.DatabaseAddToRotation(second.ID, slot.ID)
+
+	selectBanner := func(userID string) *httptest.ResponseRecorder {
+		query := url.Values{"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}}
+		if userID != "" {
+			query.Set("user_id", userID)
+		}
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/rotation?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+		h.SelectFromRotation(response, request)
+		return response
+	}
+
+	// каждый баннер показывается пользователю не больше двух раз
+	shows := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		response := selectBanner("user")
+		require.Equal(t, http.StatusOK, response.Code)
+		shows[response.Body.String()]++
+	}
+	require.Equal(t, map[string]int{strconv.Itoa(first.ID): 2, strconv.Itoa(second.ID): 2}, shows)
+	capped := selectBanner("user")
+	require.Equal(t, http.StatusNotFound, capped.Code)
+	require.Equal(t, frequencycap.ErrCapped.Error(), capped.Body.String())
+
+	// JSON-клиент отличает ограничение частоты от пустой ротации по коду ошибки
+	query := url.Values{"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}, "user_id": {"user"}}
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/rotation?"+query.Encode(), nil)
+	request.Header.Set("Accept", "application/json")
+	response := httptest.NewRecorder()
+	h.SelectFromRotation(response, request)
+	require.Equal(t, http.StatusNotFound, response.Code)
+	require.Contains(t, response.Body.String(), `"code":"frequency_capped"`)
+
+	// В пустой ротации ограниченному пользователю по-прежнему отвечает not_in_rotation
+	empty, _ := d.DatabaseCreateSlot(structures.Slot{Info: "empty"})
+	query.Set("slot_id", strconv.Itoa(empty.ID))
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, "/rotation?"+query.Encode(), nil)
+	request.Header.Set("Accept", "application/json")
+	response = httptest.NewRecorder()
+	h.SelectFromRotation(response, request)
+	require.Equal(t, http.StatusNotFound, response.Code)
+	require.Contains(t, response.Body.String(), `"code":"not_in_rotation"`)
+
+	require.Equal(t, http.StatusOK, selectBanner("other").Code)
+	require.Equal(t, http.StatusOK, selectBanner("").Code)
+}
+
+func TestSelectManyFromRotation(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	for i := 0; i < 3; i++ {
+		banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+		_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+	}
+
+	selectBanners := func(count string) *httptest.ResponseRecorder {
+		query := url.Values{"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}, "count": {count}}
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/rotation?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+		h.SelectFromRotation(response, request)
+		return response
+	}
+
+	for count, expected := range map[int]int{1: 1, 2: 2, 3: 3, 5: 3} {
+		response := selectBanners(strconv.Itoa(count))
+		require.Equal(t, http.StatusOK, response.Code)
+		require.Equal(t, "application/json", response.Header().Get("Content-Type"))
+		var banners []int
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &banners))
+		require.Len(t, banners, expected)
+		distinct := make(map[int]bool)
+		for _, id := range banners {
+			distinct[id] = true
+		}
+		require.Len(t, distinct, len(banners))
+	}
+
+	require.Equal(t, http.StatusBadRequest, selectBanners("0").Code)
+	require.Equal(t, http.StatusBadRequest, selectBanners("many").Code)
+	require.Equal(t, http.StatusBadRequest, selectBanners("1000").Code)
+}
+
+func TestSelectFromRotationResponse(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+
+	selectBanner := func(query, accept string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "/rotation?"+query, nil)
+		if accept != "" {
+			request.Header.Set("Accept", accept)
+		}
+		response := httptest.NewRecorder()
+		h.SelectFromRotation(response, request)
+		return response
+	}
+	query := fmt.Sprintf("slot_id=%d&group_id=%d", slot.ID, group.ID)
+
+	t.Run("json", func(t *testing.T) {
+		for _, accept := range []string{"application/json", "application/json, */*", "text/plain;q=0.5, application/json"} {
+			response := selectBanner(query, accept)
+			require.Equal(t, http.StatusOK, response.Code)
+			require.Equal(t, "application/json", response.Header().Get("Content-Type"))
+			var selection structures.Selection
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &selection))
+			require.Equal(t, banner.ID, selection.Banner.ID)
+			require.Equal(t, "banner", selection.Banner.Info)
+			require.Equal(t, slot.ID, selection.SlotID)
+			require.Equal(t, group.ID, selection.GroupID)
+			require.Equal(t, bannerselector.DefaultAlgorithm, selection.Algorithm)
+			require.NotEmpty(t, selection.ImpressionID)
+		}
+
+		response := selectBanner(query+"&count=2", "application/json")
+		require.Equal(t, http.StatusOK, response.Code)
+		var selections []structures.Selection
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &selections))
+		require.Len(t, selections, 1)
+		require.Equal(t, banner.ID, selections[0].Banner.ID)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		// без заголовка Accept ответ остается прежним
+		for _, accept := range []string{"", "*/*", "text/plain", "application/json;q=0.5, text/plain"} {
+			response := selectBanner(query, accept)
+			require.Equal(t, http.StatusOK, response.Code)
+			require.Equal(t, strconv.Itoa(banner.ID), response.Body.String())
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, item := range []struct {
+			query  string
+			status int
+			code   string
+		}{
+			{"slot_id=1", http.StatusBadRequest, "incorrect_query"},
+			{query + "&count=0", http.StatusBadRequest, "incorrect_count"},
+			{fmt.Sprintf("slot_id=%d&group_id=100", slot.ID), http.StatusNotFound, "not_found"},
+		} {
+			response := selectBanner(item.query, "application/json")
+			require.Equal(t, item.status, response.Code)
+			var body errorResponse
+			require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+			require.Equal(t, item.code, body.Error.Code)
+			require.NotEmpty(t, body.Error.Message)
+		}
+
+		response := selectBanner(fmt.Sprintf("slot_id=%d&group_id=100", slot.ID), "text/plain")
+		require.Equal(t, http.StatusNotFound, response.Code)
+		require.Equal(t, database.ErrNotExist.Error(), response.Body.String())
+	})
+}
+
+func TestAddToRotationJSON(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+
+	addToRotation := func() addToRotationResponse {
+		query := fmt.Sprintf("banner_id=%d&slot_id=%d", banner.ID, slot.ID)
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, "/rotation?"+query, nil)
+		request.Header.Set("Accept", "application/json")
+		response := httptest.NewRecorder()
+		h.HandlerAddToRotation(response, request)
+		require.Equal(t, http.StatusOK, response.Code)
+		var body addToRotationResponse
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+		return body
+	}
+
+	require.False(t, addToRotation().AlreadyInRotation)
+	require.True(t, addToRotation().AlreadyInRotation)
+}
+
+func TestRegisterTransitionImpression(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	signer, _ := impression.NewSigner([]byte("secret"), time.Hour)
+	h := Handlers{d, nil, nil, signer}
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+
+	do := func(handler http.HandlerFunc, method string, query url.Values) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), method, "/rotation?"+query.Encode(), nil)
+		request.Header.Set("Accept", "application/json")
+		response := httptest.NewRecorder()
+		handler(response, request)
+		return response
+	}
+	selectImpression := func() string {
+		response := do(h.SelectFromRotation, http.MethodGet,
+			url.Values{"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}})
+		require.Equal(t, http.StatusOK, response.Code)
+		var selection structures.Selection
+		require.NoError(t, json.Unmarshal(response.Body.Bytes(), &selection))
+		return selection.ImpressionID
+	}
+	clicks := func() int {
+		statistics, _ := d.DatabaseGetStatistics(structures.StatisticFilter{SlotID: slot.ID, GroupID: group.ID})
+		return statistics[0].Clicks
+	}
+	transition := url.Values{
+		"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}, "banner_id": {strconv.Itoa(banner.ID)},
+	}
+
+	// без токена показа переход не засчитывается
+	require.Equal(t, http.StatusBadRequest, do(h.RegisterTransition, http.MethodPut, transition).Code)
+
+	token := selectImpression()
+	response := do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {token}})
+	require.Equal(t, http.StatusOK, response.Code)
+	require.Equal(t, 1, clicks())
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {token}})
+	require.Equal(t, http.StatusConflict, response.Code)
+	require.Equal(t, 1, clicks())
+
+	// параметры перехода должны совпадать с показом
+	token = selectImpression()
+	transition.Set("impression_id", token)
+	transition.Set("banner_id", "100")
+	require.Equal(t, http.StatusBadRequest, do(h.RegisterTransition, http.MethodPut, transition).Code)
+	transition.Set("banner_id", strconv.Itoa(banner.ID))
+	require.Equal(t, http.StatusOK, do(h.RegisterTransition, http.MethodPut, transition).Code)
+	require.Equal(t, 2, clicks())
+
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {token + "x"}})
+	require.Equal(t, http.StatusForbidden, response.Code)
+	var body errorResponse
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	require.Equal(t, "invalid_impression", body.Error.Code)
+
+	// переход по баннеру вне ротации не расходует показ
+	token = selectImpression()
+	require.NoError(t, d.DatabaseDeleteFromRotation(banner.ID, slot.ID))
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {token}})
+	require.Equal(t, http.StatusNotFound, response.Code)
+	require.NoError(t, d.DatabaseAddToRotation(banner.ID, slot.ID))
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {token}})
+	require.Equal(t, http.StatusOK, response.Code)
+	// статистика баннера удалена вместе с ротацией
+	require.Equal(t, 1, clicks())
+
+	expired, _ := impression.NewSigner([]byte("secret"), time.Nanosecond)
+	token, _ = expired.Issue(slot.ID, group.ID, banner.ID)
+	time.Sleep(time.Millisecond)
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {token}})
+	require.Equal(t, http.StatusGone, response.Code)
+	require.Equal(t, 1, clicks())
+}
+
+func TestRegisterTransitionImpressionText(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	signer, _ := impression.NewSigner([]byte("secret"), time.Hour)
+	h := Handlers{d, nil, nil, signer}
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	for i := 0; i < 2; i++ {
+		banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+		_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+	}
+
+	// клиент без заголовка Accept получает токены показов в заголовках ответа
+	do := func(handler http.HandlerFunc, method string, query url.Values) *httptest.ResponseRecorder {
+		request, _ := http.NewRequestWithContext(context.Background(), method, "/rotation?"+query.Encode(), nil)
+		response := httptest.NewRecorder()
+		handler(response, request)
+		return response
+	}
+	selection := url.Values{"slot_id": {strconv.Itoa(slot.ID)}, "group_id": {strconv.Itoa(group.ID)}}
+	response := do(h.SelectFromRotation, http.MethodGet, selection)
+	require.Equal(t, http.StatusOK, response.Code)
+	bannerID, err := strconv.Atoi(response.Body.String())
+	require.NoError(t, err)
+	tokens := response.Header().Values(impressionHeader)
+	require.Len(t, tokens, 1)
+	shown, err := signer.Verify(tokens[0])
+	require.NoError(t, err)
+	require.Equal(t, bannerID, shown.BannerID)
+
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {tokens[0]}})
+	require.Equal(t, http.StatusOK, response.Code)
+	response = do(h.RegisterTransition, http.MethodPut, url.Values{"impression_id": {tokens[0]}})
+	require.Equal(t, http.StatusConflict, response.Code)
+	require.Equal(t, database.ErrImpressionUsed.Error(), response.Body.String())
+
+	selection.Set("count", "2")
+	response = do(h.SelectFromRotation, http.MethodGet, selection)
+	require.Equal(t, http.StatusOK, response.Code)
+	var banners []int
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &banners))
+	tokens = response.Header().Values(impressionHeader)
+	require.Len(t, tokens, len(banners))
+	for i, token := range tokens {
+		shown, err := signer.Verify(token)
+		require.NoError(t, err)
+		require.Equal(t, banners[i], shown.BannerID)
+	}
+}
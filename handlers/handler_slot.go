@@ -3,86 +3,79 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/SergeyTyurin/banner-rotation/database"
 	"github.com/SergeyTyurin/banner-rotation/structures"
 )
 
 func (h *Handlers) GetSlot(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	slot, err := h.db.DatabaseGetSlot(id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	resp, _ := json.Marshal(slot)
-	_, _ = w.Write(resp)
+	writeJSON(w, http.StatusOK, slot)
+}
+
+// GetSlots возвращает страницу списка слотов.
+func (h *Handlers) GetSlots(w http.ResponseWriter, r *http.Request) {
+	params, err := listParams(r)
+	if err != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+	list, err := h.db.DatabaseGetSlots(params)
+	writeList(w, r, list, err)
 }
 
 func (h *Handlers) CreateSlot(w http.ResponseWriter, r *http.Request) {
 	requestBody := new(bytes.Buffer)
 	_, err := requestBody.ReadFrom(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
 	var slot structures.Slot
 	err = json.Unmarshal(requestBody.Bytes(), &slot)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
 
 	createdSlot, err := h.db.DatabaseCreateSlot(slot)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
-	resp, _ := json.Marshal(createdSlot)
-	w.WriteHeader(http.StatusCreated)
-	_, _ = w.Write(resp)
+	writeJSON(w, http.StatusCreated, createdSlot)
 }
 
 func (h *Handlers) UpdateSlot(w http.ResponseWriter, r *http.Request) {
 	requestBody := new(bytes.Buffer)
 	_, err := requestBody.ReadFrom(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectBody)
 		return
 	}
-	var slot structures.Slot
-	err = json.Unmarshal(requestBody.Bytes(), &slot)
+	slot, err := mergeUpdate(requestBody.Bytes(), h.db.DatabaseGetSlot)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	err = h.db.DatabaseUpdateSlot(slot)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -90,22 +83,17 @@ func (h *Handlers) UpdateSlot(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) DeleteSlot(w http.ResponseWriter, r *http.Request) {
 	if !r.URL.Query().Has("id") {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	id, err := strconv.Atoi(r.URL.Query().Get("id"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, errIncorrectQuery)
 		return
 	}
 	err = h.db.DatabaseDeleteSlot(id)
 	if err != nil {
-		if errors.Is(err, database.ErrNotExist) {
-			w.WriteHeader(http.StatusNotFound)
-		} else {
-			w.WriteHeader(http.StatusInternalServerError)
-		}
-		_, _ = w.Write([]byte(err.Error()))
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
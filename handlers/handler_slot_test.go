@@ -16,14 +16,14 @@ import (
 )
 
 func TestBadRequestSlot(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/slot")
 
 	t.Run("create", func(t *testing.T) {
@@ -44,6 +44,16 @@ func TestBadRequestSlot(t *testing.T) {
 		require.Equal(t, http.StatusBadRequest, response.Code)
 	})
 
+	t.Run("create with unknown algorithm", func(t *testing.T) {
+		entity := structures.Slot{Info: "entity", Strategy: structures.Strategy{Algorithm: "unknown"}}
+		jsonBody, _ := json.Marshal(entity)
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(jsonBody))
+		response := httptest.NewRecorder()
+
+		h.CreateSlot(response, request)
+		require.Equal(t, http.StatusBadRequest, response.Code)
+	})
+
 	t.Run("delete", func(t *testing.T) {
 		request, _ := http.NewRequestWithContext(context.Background(), http.MethodDelete, url, nil)
 		response := httptest.NewRecorder()
@@ -70,14 +80,14 @@ func TestBadRequestSlot(t *testing.T) {
 }
 
 func TestNonExistsSlot(t *testing.T) {
-	d := database.NewDatabase()
+	d := database.NewMemoryDatabase()
 	config, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
 	closeConnection, _ := d.DatabaseConnect(config)
 	defer func() {
 		_ = closeConnection()
 	}()
 
-	h := Handlers{d, nil}
+	h := Handlers{d, nil, nil, nil}
 	url := fmt.Sprintf("http://%s:%d/%s", config.Host(), config.Port(), "/slot")
 
 	t.Run("update", func(t *testing.T) {
@@ -114,3 +124,51 @@ func TestNonExistsSlot(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, response.Code)
 	})
 }
+
+func TestUpdateSlotPartial(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	epsilon := 0.2
+	slot, err := d.DatabaseCreateSlot(structures.Slot{
+		Info:      "slot",
+		Width:     300,
+		Height:    250,
+		Placement: "top",
+		Strategy:  structures.Strategy{Algorithm: "epsilon_greedy", Epsilon: &epsilon},
+	})
+	require.NoError(t, err)
+
+	update := func(body string) int {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPut, "/slot",
+			bytes.NewReader([]byte(body)))
+		response := httptest.NewRecorder()
+		h.UpdateSlot(response, request)
+		return response.Code
+	}
+
+	t.Run("only sent fields", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, update(fmt.Sprintf(`{"id": %d, "info": "updated"}`, slot.ID)))
+		updated, err := d.DatabaseGetSlot(slot.ID)
+		require.NoError(t, err)
+		require.Equal(t, "updated", updated.Info)
+		require.Equal(t, 300, updated.Width)
+		require.Equal(t, 250, updated.Height)
+		require.Equal(t, "top", updated.Placement)
+		require.Equal(t, "epsilon_greedy", updated.Strategy.Algorithm)
+		require.NotNil(t, updated.Strategy.Epsilon)
+		require.Equal(t, 0.2, *updated.Strategy.Epsilon)
+	})
+	t.Run("null resets param", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, update(fmt.Sprintf(`{"id": %d, "strategy": {"epsilon": null}}`, slot.ID)))
+		updated, err := d.DatabaseGetSlot(slot.ID)
+		require.NoError(t, err)
+		require.Equal(t, "epsilon_greedy", updated.Strategy.Algorithm)
+		require.Nil(t, updated.Strategy.Epsilon)
+	})
+	t.Run("incorrect merged strategy", func(t *testing.T) {
+		require.Equal(t, http.StatusBadRequest, update(fmt.Sprintf(`{"id": %d, "strategy": {"epsilon": 2}}`, slot.ID)))
+		stored, err := d.DatabaseGetSlot(slot.ID)
+		require.NoError(t, err)
+		require.Nil(t, stored.Strategy.Epsilon)
+	})
+}
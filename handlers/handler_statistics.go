@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SergeyTyurin/banner-rotation/structures"
+)
+
+// optionalID возвращает значение необязательного параметра-идентификатора или 0, если он не задан.
+func optionalID(r *http.Request, name string) (int, error) {
+	if !r.URL.Query().Has(name) {
+		return 0, nil
+	}
+	return strconv.Atoi(r.URL.Query().Get(name))
+}
+
+// optionalTime возвращает значение необязательного параметра времени в формате RFC 3339.
+func optionalTime(r *http.Request, name string) (time.Time, error) {
+	if !r.URL.Query().Has(name) {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, r.URL.Query().Get(name))
+}
+
+func (h *Handlers) GetStatistics(w http.ResponseWriter, r *http.Request) {
+	slotID, slotErr := optionalID(r, "slot_id")
+	groupID, groupErr := optionalID(r, "group_id")
+	bannerID, bannerErr := optionalID(r, "banner_id")
+	if slotErr != nil || groupErr != nil || bannerErr != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+
+	filter := structures.StatisticFilter{SlotID: slotID, GroupID: groupID, BannerID: bannerID}
+	statistics, err := h.db.DatabaseGetStatistics(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, statistics)
+}
+
+func (h *Handlers) GetStatisticsHistory(w http.ResponseWriter, r *http.Request) {
+	slotID, slotErr := optionalID(r, "slot_id")
+	groupID, groupErr := optionalID(r, "group_id")
+	bannerID, bannerErr := optionalID(r, "banner_id")
+	from, fromErr := optionalTime(r, "from")
+	to, toErr := optionalTime(r, "to")
+	if slotErr != nil || groupErr != nil || bannerErr != nil || fromErr != nil || toErr != nil {
+		writeError(w, r, errIncorrectQuery)
+		return
+	}
+
+	filter := structures.HistoryFilter{
+		StatisticFilter: structures.StatisticFilter{SlotID: slotID, GroupID: groupID, BannerID: bannerID},
+		From:            from,
+		To:              to,
+		Granularity:     r.URL.Query().Get("granularity"),
+	}
+	history, err := h.db.DatabaseGetStatisticsHistory(filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/structures"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStatistics(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	url := "http://127.0.0.1/statistics"
+	first, _ := d.DatabaseCreateBanner(structures.Banner{Info: "first"})
+	second, _ := d.DatabaseCreateBanner(structures.Banner{Info: "second"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(first.ID, slot.ID)
+	_ = d.DatabaseAddToRotation(second.ID, slot.ID)
+	for i := 0; i < 4; i++ {
+		_, _ = database.SelectFromRotation(d, slot.ID, group.ID)
+	}
+	_ = d.DatabaseRegisterTransition(slot.ID, first.ID, group.ID)
+
+	get := func(query string) (int, []structures.Statistic) {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+query, nil)
+		response := httptest.NewRecorder()
+		h.GetStatistics(response, request)
+		var statistics []structures.Statistic
+		_ = json.Unmarshal(response.Body.Bytes(), &statistics)
+		return response.Code, statistics
+	}
+
+	t.Run("all", func(t *testing.T) {
+		code, statistics := get("")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, statistics, 2)
+		require.Equal(t, first.ID, statistics[0].BannerID)
+		require.Equal(t, 2, statistics[0].Displays)
+		require.Equal(t, 1, statistics[0].Clicks)
+		require.InDelta(t, 0.5, statistics[0].CTR, 1e-9)
+		require.NotNil(t, statistics[0].Score)
+		require.Greater(t, *statistics[0].Score, *statistics[1].Score)
+	})
+
+	t.Run("filter by banner", func(t *testing.T) {
+		code, statistics := get("?banner_id=2&slot_id=1&group_id=1")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, statistics, 1)
+		require.Equal(t, second.ID, statistics[0].BannerID)
+		require.Equal(t, 0, statistics[0].Clicks)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		code, statistics := get("?group_id=100")
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, statistics)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		code, _ := get("?slot_id=q")
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
+
+func TestGetStatisticsHistory(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	h := Handlers{d, nil, nil, nil}
+	url := "http://127.0.0.1/statistics/history"
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+	_, _ = database.SelectFromRotation(d, slot.ID, group.ID)
+	_, _ = database.SelectFromRotation(d, slot.ID, group.ID)
+	_ = d.DatabaseRegisterTransition(slot.ID, banner.ID, group.ID)
+
+	get := func(query string) (int, []structures.HistoryPoint) {
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+query, nil)
+		response := httptest.NewRecorder()
+		h.GetStatisticsHistory(response, request)
+		var history []structures.HistoryPoint
+		_ = json.Unmarshal(response.Body.Bytes(), &history)
+		return response.Code, history
+	}
+
+	t.Run("daily", func(t *testing.T) {
+		code, history := get("?granularity=day&slot_id=1")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, history, 1)
+		require.Equal(t, 2, history[0].Displays)
+		require.Equal(t, 1, history[0].Clicks)
+		require.InDelta(t, 0.5, history[0].CTR, 1e-9)
+	})
+
+	t.Run("range", func(t *testing.T) {
+		code, history := get("?from=2000-01-01T00:00:00Z&to=2000-01-02T00:00:00Z")
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, history)
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		code, _ := get("?granularity=week")
+		require.Equal(t, http.StatusBadRequest, code)
+		code, _ = get("?from=yesterday")
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+}
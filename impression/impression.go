@@ -0,0 +1,107 @@
+package impression
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIncorrectParams = errors.New("incorrect impression params")
+	ErrInvalidToken    = errors.New("invalid impression token")
+	ErrExpiredToken    = errors.New("impression token expired")
+)
+
+// now - источник текущего времени для срока действия токенов.
+var now = time.Now
+
+// Impression - показ баннера в слоте пользователю группы, подтвержденный токеном.
+type Impression struct {
+	ID        string
+	SlotID    int
+	GroupID   int
+	BannerID  int
+	ExpiresAt time.Time
+}
+
+// Signer выдает токены показов и проверяет их подпись и срок действия.
+type Signer interface {
+	// Issue возвращает подписанный токен нового показа.
+	Issue(slotID, groupID, bannerID int) (string, error)
+	// Verify возвращает показ, подтвержденный токеном.
+	Verify(token string) (Impression, error)
+}
+
+type signerImpl struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner создает подпись токенов HMAC-SHA256 с ключом secret, токен действует ttl.
+func NewSigner(secret []byte, ttl time.Duration) (Signer, error) {
+	if len(secret) == 0 || ttl <= 0 {
+		return nil, ErrIncorrectParams
+	}
+	return &signerImpl{secret: secret, ttl: ttl}, nil
+}
+
+func (s *signerImpl) Issue(slotID, groupID, bannerID int) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	// Токен - показ в виде id:slot:group:banner:expires и его подпись
+	payload := fmt.Sprintf("%s:%d:%d:%d:%d",
+		hex.EncodeToString(id), slotID, groupID, bannerID, now().Add(s.ttl).Unix())
+	return encode([]byte(payload)) + "." + encode(s.sign(payload)), nil
+}
+
+func (s *signerImpl) Verify(token string) (Impression, error) {
+	var impression Impression
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return impression, ErrInvalidToken
+	}
+	payload, payloadErr := base64.RawURLEncoding.DecodeString(encodedPayload)
+	signature, signatureErr := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if payloadErr != nil || signatureErr != nil || !hmac.Equal(signature, s.sign(string(payload))) {
+		return impression, ErrInvalidToken
+	}
+
+	fields := strings.Split(string(payload), ":")
+	if len(fields) != 5 {
+		return impression, ErrInvalidToken
+	}
+	impression.ID = fields[0]
+	values := make([]int64, 0, len(fields)-1)
+	for _, field := range fields[1:] {
+		value, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return impression, ErrInvalidToken
+		}
+		values = append(values, value)
+	}
+	impression.SlotID, impression.GroupID, impression.BannerID = int(values[0]), int(values[1]), int(values[2])
+	impression.ExpiresAt = time.Unix(values[3], 0)
+	if !now().Before(impression.ExpiresAt) {
+		return impression, ErrExpiredToken
+	}
+	return impression, nil
+}
+
+func (s *signerImpl) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
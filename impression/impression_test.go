@@ -0,0 +1,74 @@
+package impression
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setNow(t *testing.T, current *time.Time) {
+	t.Helper()
+	previous := now
+	now = func() time.Time { return *current }
+	t.Cleanup(func() { now = previous })
+}
+
+func TestSigner(t *testing.T) {
+	current := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	setNow(t, &current)
+	signer, err := NewSigner([]byte("secret"), time.Hour)
+	require.NoError(t, err)
+
+	t.Run("issue and verify", func(t *testing.T) {
+		token, err := signer.Issue(1, 2, 3)
+		require.NoError(t, err)
+		impression, err := signer.Verify(token)
+		require.NoError(t, err)
+		require.NotEmpty(t, impression.ID)
+		require.Equal(t, 1, impression.SlotID)
+		require.Equal(t, 2, impression.GroupID)
+		require.Equal(t, 3, impression.BannerID)
+		require.True(t, current.Add(time.Hour).Equal(impression.ExpiresAt))
+
+		other, _ := signer.Issue(1, 2, 3)
+		require.NotEqual(t, token, other)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		token, _ := signer.Issue(1, 2, 3)
+		current = current.Add(time.Hour)
+		_, err := signer.Verify(token)
+		require.ErrorIs(t, err, ErrExpiredToken)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		token, _ := signer.Issue(1, 2, 3)
+		payload, signature, _ := strings.Cut(token, ".")
+		forged := encode([]byte(strings.Replace(decode(t, payload), ":3:", ":4:", 1))) + "." + signature
+
+		otherSigner, _ := NewSigner([]byte("other"), time.Hour)
+		otherToken, _ := otherSigner.Issue(1, 2, 3)
+
+		for _, token := range []string{"", "token", payload, forged, otherToken} {
+			_, err := signer.Verify(token)
+			require.ErrorIs(t, err, ErrInvalidToken, token)
+		}
+	})
+
+	t.Run("incorrect params", func(t *testing.T) {
+		_, err := NewSigner(nil, time.Hour)
+		require.ErrorIs(t, err, ErrIncorrectParams)
+		_, err = NewSigner([]byte("secret"), 0)
+		require.ErrorIs(t, err, ErrIncorrectParams)
+	})
+}
+
+func decode(t *testing.T, data string) string {
+	t.Helper()
+	decoded, err := base64.RawURLEncoding.DecodeString(data)
+	require.NoError(t, err)
+	return string(decoded)
+}
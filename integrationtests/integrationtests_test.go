@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/SergeyTyurin/banner-rotation/configs"
+	"github.com/SergeyTyurin/banner-rotation/database"
 	"github.com/SergeyTyurin/banner-rotation/messagebroker"
 	"github.com/SergeyTyurin/banner-rotation/structures"
 	"github.com/stretchr/testify/require"
@@ -128,6 +131,28 @@ func TestBanner(t *testing.T) {
 		_ = json.Unmarshal(responceBody.Bytes(), &fromDB)
 		require.Equal(t, created.ID, fromDB.ID)
 	})
+
+	t.Run("list", func(t *testing.T) {
+		created := createBanner(url)
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+		q := request.URL.Query()
+		q.Add("sort", "-id")
+		q.Add("limit", "1")
+		request.URL.RawQuery = q.Encode()
+		response, err := http.DefaultClient.Do(request)
+		defer func() {
+			_ = response.Body.Close()
+		}()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		responceBody := new(bytes.Buffer)
+		_, _ = responceBody.ReadFrom(response.Body)
+		var banners []structures.Banner
+		_ = json.Unmarshal(responceBody.Bytes(), &banners)
+		require.Len(t, banners, 1)
+		require.Equal(t, created.ID, banners[0].ID)
+	})
 }
 
 func TestSlot(t *testing.T) {
@@ -277,6 +302,68 @@ func TestRotation(t *testing.T) {
 		require.Equal(t, http.StatusOK, response.StatusCode)
 	})
 
+	t.Run("rotation members", func(t *testing.T) {
+		banner := createBanner(url + "/banner")
+		slot := createSlot(url + "/slot")
+		addToRotation(url+"/rotation", banner.ID, slot.ID)
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation/members", nil)
+		q := request.URL.Query()
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		request.URL.RawQuery = q.Encode()
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer func() {
+			_ = response.Body.Close()
+		}()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		var members []structures.RotationMember
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&members))
+		require.Len(t, members, 1)
+		require.Equal(t, banner.ID, members[0].Banner.ID)
+		require.Equal(t, "active", members[0].Status)
+	})
+
+	t.Run("add to rotation again", func(t *testing.T) {
+		banner := createBanner(url + "/banner")
+		slot := createSlot(url + "/slot")
+		_ = createGroup(url + "/group")
+		addToRotation(url+"/rotation", banner.ID, slot.ID)
+		for strict, status := range map[string]int{"false": http.StatusOK, "true": http.StatusConflict} {
+			request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, url+"/rotation", nil)
+			q := request.URL.Query()
+			q.Add("banner_id", strconv.Itoa(banner.ID))
+			q.Add("slot_id", strconv.Itoa(slot.ID))
+			q.Add("strict", strict)
+			request.URL.RawQuery = q.Encode()
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			require.Equal(t, status, response.StatusCode)
+		}
+	})
+
+	t.Run("select for group created after rotation", func(t *testing.T) {
+		banner := createBanner(url + "/banner")
+		slot := createSlot(url + "/slot")
+		addToRotation(url+"/rotation", banner.ID, slot.ID)
+		group := createGroup(url + "/group")
+
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation", nil)
+		q := request.URL.Query()
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		q.Add("group_id", strconv.Itoa(group.ID))
+		request.URL.RawQuery = q.Encode()
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		responceBody := new(bytes.Buffer)
+		_, _ = responceBody.ReadFrom(response.Body)
+		require.Equal(t, strconv.Itoa(banner.ID), responceBody.String())
+	})
+
 	t.Run("register transition", func(t *testing.T) {
 		banner := createBanner(url + "/banner")
 		slot := createSlot(url + "/slot")
@@ -302,6 +389,80 @@ func TestRotation(t *testing.T) {
 		require.Equal(t, expected, msg)
 	})
 
+	t.Run("priority", func(t *testing.T) {
+		banner := createBanner(url + "/banner")
+		slot := createSlot(url + "/slot")
+		for share, status := range map[string]int{"0.5": http.StatusOK, "1.5": http.StatusBadRequest} {
+			request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, url+"/rotation", nil)
+			q := request.URL.Query()
+			q.Add("banner_id", strconv.Itoa(banner.ID))
+			q.Add("slot_id", strconv.Itoa(slot.ID))
+			q.Add("weight", "2")
+			q.Add("share", share)
+			request.URL.RawQuery = q.Encode()
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			_ = response.Body.Close()
+			require.Equal(t, status, response.StatusCode)
+		}
+	})
+
+	t.Run("flight", func(t *testing.T) {
+		banner := createBanner(url + "/banner")
+		slot := createSlot(url + "/slot")
+		group := createGroup(url + "/group")
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, url+"/rotation", nil)
+		q := request.URL.Query()
+		q.Add("banner_id", strconv.Itoa(banner.ID))
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		q.Add("start_at", time.Now().Add(time.Hour).Format(time.RFC3339))
+		request.URL.RawQuery = q.Encode()
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		// период показов еще не начался
+		request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation", nil)
+		q = request.URL.Query()
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		q.Add("group_id", strconv.Itoa(group.ID))
+		request.URL.RawQuery = q.Encode()
+		response, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+
+	t.Run("pause banner", func(t *testing.T) {
+		banner := createBanner(url + "/banner")
+		slot := createSlot(url + "/slot")
+		group := createGroup(url + "/group")
+		addToRotation(url+"/rotation", banner.ID, slot.ID)
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, url+"/rotation/pause", nil)
+		q := request.URL.Query()
+		q.Add("banner_id", strconv.Itoa(banner.ID))
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		request.URL.RawQuery = q.Encode()
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		msg, err := broker.GetBannerStatusEvent()
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("banner_id=%d, slot_id=%d, status=paused", banner.ID, slot.ID), msg)
+
+		request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation", nil)
+		q = request.URL.Query()
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		q.Add("group_id", strconv.Itoa(group.ID))
+		request.URL.RawQuery = q.Encode()
+		response, err = http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+
 	t.Run("delete from rotation", func(t *testing.T) {
 		banner := createBanner(url + "/banner")
 		slot := createSlot(url + "/slot")
@@ -349,4 +510,161 @@ func TestRotation(t *testing.T) {
 			slot.ID, group.ID, banner.ID)
 		require.Equal(t, expected, msg)
 	})
+
+	t.Run("select several banners", func(t *testing.T) {
+		slot := createSlot(url + "/slot")
+		group := createGroup(url + "/group")
+		for i := 0; i < 3; i++ {
+			banner := createBanner(url + "/banner")
+			addToRotation(url+"/rotation", banner.ID, slot.ID)
+		}
+		request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation", nil)
+		q := request.URL.Query()
+		q.Add("slot_id", strconv.Itoa(slot.ID))
+		q.Add("group_id", strconv.Itoa(group.ID))
+		q.Add("count", "2")
+		request.URL.RawQuery = q.Encode()
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		var banners []int
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&banners))
+		require.Len(t, banners, 2)
+		require.NotEqual(t, banners[0], banners[1])
+		for range banners {
+			_, err := broker.GetSelectFromRotationEvent()
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestConcurrentSelectFromRotation(t *testing.T) {
+	config, _ := configs.GetAppSettings("../config/test/test_connection_config.yaml")
+	url := fmt.Sprintf("http://%s:%d", config.Host(), config.Port())
+	slot := createSlot(url + "/slot")
+	group := createGroup(url + "/group")
+	banners := []structures.Banner{createBanner(url + "/banner"), createBanner(url + "/banner")}
+	for _, banner := range banners {
+		addToRotation(url+"/rotation", banner.ID, slot.ID)
+	}
+
+	workers, perWorker := 10, 20
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	selected := make(map[int]int)
+	errs := make(chan error, workers*perWorker)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation", nil)
+				q := request.URL.Query()
+				q.Add("slot_id", strconv.Itoa(slot.ID))
+				q.Add("group_id", strconv.Itoa(group.ID))
+				request.URL.RawQuery = q.Encode()
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				responseBody := new(bytes.Buffer)
+				_, _ = responseBody.ReadFrom(response.Body)
+				_ = response.Body.Close()
+				if response.StatusCode != http.StatusOK {
+					errs <- fmt.Errorf("status %d: %s", response.StatusCode, responseBody.String())
+					continue
+				}
+				selectedID, err := strconv.Atoi(responseBody.String())
+				if err != nil {
+					errs <- err
+					continue
+				}
+				mu.Lock()
+				selected[selectedID]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Каждый запрос учтен как показ, а выбор опирается на актуальные счетчики
+	total := 0
+	for _, banner := range banners {
+		require.Equal(t, workers*perWorker/len(banners), selected[banner.ID])
+		total += selected[banner.ID]
+	}
+	require.Equal(t, workers*perWorker, total)
+
+	// Показы в таблице Statistic совпадают с числом успешных ответов
+	dbConfig, _ := configs.GetDBConnectionConfig("../config/test/test_connection_config.yaml")
+	db := database.NewDatabase()
+	closeConnection, err := db.DatabaseConnect(dbConfig)
+	require.NoError(t, err)
+	defer func() {
+		_ = closeConnection()
+	}()
+	statistics, err := db.DatabaseGetStatistics(structures.StatisticFilter{SlotID: slot.ID, GroupID: group.ID})
+	require.NoError(t, err)
+	displays := 0
+	for _, statistic := range statistics {
+		displays += statistic.Displays
+	}
+	require.Equal(t, total, displays)
+}
+
+func TestStatistics(t *testing.T) {
+	config, _ := configs.GetAppSettings("../config/test/test_connection_config.yaml")
+	url := fmt.Sprintf("http://%s:%d", config.Host(), config.Port())
+	banner := createBanner(url + "/banner")
+	slot := createSlot(url + "/slot")
+	group := createGroup(url + "/group")
+	addToRotation(url+"/rotation", banner.ID, slot.ID)
+
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/rotation", nil)
+	q := request.URL.Query()
+	q.Add("slot_id", strconv.Itoa(slot.ID))
+	q.Add("group_id", strconv.Itoa(group.ID))
+	request.URL.RawQuery = q.Encode()
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/statistics", nil)
+	q = request.URL.Query()
+	q.Add("slot_id", strconv.Itoa(slot.ID))
+	q.Add("group_id", strconv.Itoa(group.ID))
+	request.URL.RawQuery = q.Encode()
+	response, err = http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var statistics []structures.Statistic
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&statistics))
+	require.Len(t, statistics, 1)
+	require.Equal(t, banner.ID, statistics[0].BannerID)
+	require.Equal(t, 1, statistics[0].Displays)
+	require.NotNil(t, statistics[0].Score)
+
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, url+"/statistics/history", nil)
+	q = request.URL.Query()
+	q.Add("slot_id", strconv.Itoa(slot.ID))
+	q.Add("granularity", "day")
+	request.URL.RawQuery = q.Encode()
+	historyResponse, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer historyResponse.Body.Close()
+	require.Equal(t, http.StatusOK, historyResponse.StatusCode)
+
+	var history []structures.HistoryPoint
+	require.NoError(t, json.NewDecoder(historyResponse.Body).Decode(&history))
+	require.Len(t, history, 1)
+	require.Equal(t, 1, history[0].Displays)
 }
@@ -1,24 +1,112 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/SergeyTyurin/banner-rotation/configs"
 	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/frequencycap"
+	"github.com/SergeyTyurin/banner-rotation/impression"
 	"github.com/SergeyTyurin/banner-rotation/messagebroker"
 	"github.com/SergeyTyurin/banner-rotation/router"
 )
 
+func newDatabase(storage string) (database.Database, error) {
+	switch storage {
+	case "memory":
+		return database.NewMemoryDatabase(), nil
+	case "postgres":
+		statConfig, err := configs.GetStatisticsConfig("config/connection_config.yaml")
+		if err != nil {
+			return nil, err
+		}
+		return database.NewStatisticsDatabase(statConfig)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", storage)
+	}
+}
+
+// newBroker подключается к брокеру сообщений. В режиме memory сервис запускается
+// без внешних зависимостей, события не отправляются.
+func newBroker(storage string) (messagebroker.MessageBroker, func(), error) {
+	if storage == "memory" {
+		return nil, func() {}, nil
+	}
+	msgConfig, err := configs.GetMessageBrokerConfig("config/connection_config.yaml")
+	if err != nil {
+		return nil, nil, err
+	}
+	broker := messagebroker.NewBroker()
+	closeBroker, err := broker.Connect(msgConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return broker, closeBroker, nil
+}
+
+// newCapper создает ограничение частоты показов, если оно задано в конфигурации.
+func newCapper(config configs.FrequencyCapConfig) (frequencycap.Capper, error) {
+	if config.Limit() == 0 {
+		return nil, nil
+	}
+	return frequencycap.NewCapper(frequencycap.NewMemoryStore(config.Window()), config.Limit(), config.Window())
+}
+
+// newSigner создает подпись токенов показов, если задан ключ IMPRESSION_SECRET.
+// Без ключа переходы засчитываются без подтверждения показа.
+func newSigner(config configs.ImpressionConfig) (impression.Signer, error) {
+	secret := os.Getenv("IMPRESSION_SECRET")
+	if secret == "" {
+		log.Println("warning: IMPRESSION_SECRET is not set, transitions are accepted without impression tokens")
+		return nil, nil
+	}
+	return impression.NewSigner([]byte(secret), config.TTL())
+}
+
+// runMigrate выполняет подкоманду migrate up|down [steps].
+func runMigrate(dbConfig configs.DBConnectionConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [steps]")
+	}
+	steps := 1
+	if len(args) > 1 {
+		var err error
+		if steps, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("incorrect steps %q: %w", args[1], err)
+		}
+	}
+	return database.Migrate(dbConfig, args[0], steps)
+}
+
 func main() {
+	storage := flag.String("storage", "postgres", "storage for entities and statistics: postgres or memory")
+	flag.Parse()
+
 	dbConfig, err := configs.GetDBConnectionConfig("config/connection_config.yaml")
 	if err != nil {
 		log.Fatal(err)
 	}
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(dbConfig, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	// Подключение в БД
-	db := database.NewDatabase()
+	db, err := newDatabase(*storage)
+	if err != nil {
+		log.Fatal(err)
+	}
 	closeFunc, err := db.DatabaseConnect(dbConfig)
 	if err != nil {
 		log.Fatal(err)
@@ -29,37 +117,65 @@ func main() {
 		}
 	}()
 
-	msgConfig, err := configs.GetMessageBrokerConfig("config/connection_config.yaml")
+	broker, closeBroker, err := newBroker(*storage)
 	if err != nil {
 		log.Println(err)
 		return
 	}
+	defer closeBroker()
 
-	broker := messagebroker.NewBroker()
-	closeBroker, err := broker.Connect(msgConfig)
+	appConfig, err := configs.GetAppSettings("config/connection_config.yaml")
 	if err != nil {
 		log.Println(err)
 		return
 	}
-	defer closeBroker()
-
-	appConfig, err := configs.GetAppSettings("config/connection_config.yaml")
+	capConfig, err := configs.GetFrequencyCapConfig("config/connection_config.yaml")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	capper, err := newCapper(capConfig)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	impressionConfig, err := configs.GetImpressionConfig("config/connection_config.yaml")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	signer, err := newSigner(impressionConfig)
 	if err != nil {
 		log.Println(err)
 		return
 	}
 	// Создание сервера с мультиплексором запросов
-	muxRouter := router.NewRouter(db, broker)
+	muxRouter := router.NewRouter(db, broker, capper, signer)
 	server := http.Server{
 		Addr:              fmt.Sprintf("%s:%d", appConfig.Host(), appConfig.Port()),
 		Handler:           muxRouter.CustomMux(),
 		ReadHeaderTimeout: 30 * time.Second,
 	}
-	defer server.Close()
+
+	// Остановка сервера по сигналу, чтобы успеть сохранить статистику
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Println(err)
+		}
+	}()
+
 	log.Println("listening...")
 	// Прослушивание сервера
-	if err := server.ListenAndServe(); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Println(err)
 		return
 	}
+	<-shutdownDone
 }
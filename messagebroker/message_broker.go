@@ -15,9 +15,11 @@ type MessageBroker interface {
 	Connect(configs.MessageBrokerConfig) (func(), error)
 	SendRegisterTransitionEvent(string) error
 	SendSelectFromRotationEvent(string) error
+	SendBannerStatusEvent(string) error
 
 	GetRegisterTransitionEvent() (string, error)
 	GetSelectFromRotationEvent() (string, error)
+	GetBannerStatusEvent() (string, error)
 }
 
 type messageBrokerImpl struct {
@@ -25,6 +27,7 @@ type messageBrokerImpl struct {
 	ch            *amqp.Channel
 	registerQueue amqp.Queue
 	selectQueue   amqp.Queue
+	statusQueue   amqp.Queue
 }
 
 func NewBroker() MessageBroker {
@@ -76,10 +79,25 @@ func (m *messageBrokerImpl) Connect(config configs.MessageBrokerConfig) (func(),
 		return nil, err
 	}
 
+	stq, err := ch.QueueDeclare(
+		"BannerStatus", // name
+		false,          // durable
+		false,          // delete when unused
+		false,          // exclusive
+		false,          // no-wait
+		nil,            // arguments
+	)
+	if err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, err
+	}
+
 	m.conn = conn
 	m.ch = ch
 	m.registerQueue = rq
 	m.selectQueue = sq
+	m.statusQueue = stq
 
 	return func() {
 		m.ch.Close()
@@ -121,6 +139,24 @@ func (m *messageBrokerImpl) SendSelectFromRotationEvent(body string) error {
 	return nil
 }
 
+// SendBannerStatusEvent отправляет событие приостановки или возобновления показов баннера.
+func (m *messageBrokerImpl) SendBannerStatusEvent(body string) error {
+	err := m.ch.PublishWithContext(context.Background(),
+		"",                 // exchange
+		m.statusQueue.Name, // routing key
+		false,              // mandatory
+		false,              // immediate
+		amqp.Publishing{
+			ContentType: "text/plain",
+			Body:        []byte(body),
+		})
+	if err != nil {
+		return err
+	}
+	log.Printf(" [x] Sent %s\n", body)
+	return nil
+}
+
 func (m *messageBrokerImpl) GetSelectFromRotationEvent() (string, error) {
 	msgs, err := m.ch.Consume(
 		m.selectQueue.Name, // queue
@@ -158,3 +194,22 @@ func (m *messageBrokerImpl) GetRegisterTransitionEvent() (string, error) {
 	}
 	return "", nil
 }
+
+func (m *messageBrokerImpl) GetBannerStatusEvent() (string, error) {
+	msgs, err := m.ch.Consume(
+		m.statusQueue.Name, // queue
+		"",                 // consumer
+		true,               // auto-ack
+		false,              // exclusive
+		false,              // no-local
+		false,              // no-wait
+		nil,                // args
+	)
+	if err != nil {
+		return "", err
+	}
+	for d := range msgs {
+		return string(d.Body), nil
+	}
+	return "", nil
+}
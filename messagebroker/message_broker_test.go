@@ -16,6 +16,7 @@ func TestSendMessages(t *testing.T) {
 
 	require.NoError(t, m.SendRegisterTransitionEvent("test regsiter"))
 	require.NoError(t, m.SendSelectFromRotationEvent("test select"))
+	require.NoError(t, m.SendBannerStatusEvent("test status"))
 
 	msg, err := m.GetRegisterTransitionEvent()
 	require.NoError(t, err)
@@ -24,4 +25,8 @@ func TestSendMessages(t *testing.T) {
 	msg, err = m.GetSelectFromRotationEvent()
 	require.NoError(t, err)
 	require.Equal(t, msg, "test select")
+
+	msg, err = m.GetBannerStatusEvent()
+	require.NoError(t, err)
+	require.Equal(t, msg, "test status")
 }
@@ -3,16 +3,22 @@ package router
 import (
 	"net/http"
 	"strings"
+
+	"github.com/SergeyTyurin/banner-rotation/handlers"
 )
 
 func (router *routerImpl) handleGroupsFunc(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimRight(r.URL.Path, "/") != "/group" {
-		http.NotFound(w, r)
+		handlers.NotFound(w, r)
 		return
 	}
 	switch r.Method {
 	case http.MethodGet:
-		router.handlers.GetGroup(w, r) // Получить группу пользователей
+		if r.URL.Query().Has("id") {
+			router.handlers.GetGroup(w, r) // Получить группу пользователей
+		} else {
+			router.handlers.GetGroups(w, r) // Получить список групп
+		}
 	case http.MethodPost:
 		router.handlers.CreateGroup(w, r) // Создание новой группы
 	case http.MethodPut:
@@ -20,18 +26,22 @@ func (router *routerImpl) handleGroupsFunc(w http.ResponseWriter, r *http.Reques
 	case http.MethodDelete:
 		router.handlers.DeleteGroup(w, r) // Удаление группы
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.MethodNotAllowed(w, r)
 	}
 }
 
 func (router *routerImpl) handleSlotsFunc(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimRight(r.URL.Path, "/") != "/slot" {
-		http.NotFound(w, r)
+		handlers.NotFound(w, r)
 		return
 	}
 	switch r.Method {
 	case http.MethodGet:
-		router.handlers.GetSlot(w, r) // Получить слот
+		if r.URL.Query().Has("id") {
+			router.handlers.GetSlot(w, r) // Получить слот
+		} else {
+			router.handlers.GetSlots(w, r) // Получить список слотов
+		}
 	case http.MethodPost:
 		router.handlers.CreateSlot(w, r) // Создание нового слота
 	case http.MethodPut:
@@ -39,13 +49,13 @@ func (router *routerImpl) handleSlotsFunc(w http.ResponseWriter, r *http.Request
 	case http.MethodDelete:
 		router.handlers.DeleteSlot(w, r) // Удаление слота
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.MethodNotAllowed(w, r)
 	}
 }
 
 func (router *routerImpl) handleRotationFunc(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimRight(r.URL.Path, "/") != "/rotation" {
-		http.NotFound(w, r)
+		handlers.NotFound(w, r)
 		return
 	}
 	switch r.Method {
@@ -58,18 +68,22 @@ func (router *routerImpl) handleRotationFunc(w http.ResponseWriter, r *http.Requ
 	case http.MethodDelete:
 		router.handlers.DeleteFromRotation(w, r) // Удаление баннера из ротации
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.MethodNotAllowed(w, r)
 	}
 }
 
 func (router *routerImpl) handleBannersFunc(w http.ResponseWriter, r *http.Request) {
 	if strings.TrimRight(r.URL.Path, "/") != "/banner" {
-		http.NotFound(w, r)
+		handlers.NotFound(w, r)
 		return
 	}
 	switch r.Method {
 	case http.MethodGet:
-		router.handlers.GetBanner(w, r) // Получение баннер
+		if r.URL.Query().Has("id") {
+			router.handlers.GetBanner(w, r) // Получение баннер
+		} else {
+			router.handlers.GetBanners(w, r) // Получить список баннеров
+		}
 	case http.MethodPost:
 		router.handlers.CreateBanner(w, r) // Создание нового баннера
 	case http.MethodPut:
@@ -77,6 +91,71 @@ func (router *routerImpl) handleBannersFunc(w http.ResponseWriter, r *http.Reque
 	case http.MethodDelete:
 		router.handlers.DeleteBanner(w, r) // Удаление баннера
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		handlers.MethodNotAllowed(w, r)
+	}
+}
+
+func (router *routerImpl) handleRotationMembersFunc(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimRight(r.URL.Path, "/") != "/rotation/members" {
+		handlers.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		router.handlers.GetRotationMembers(w, r) // Состав ротации
+	default:
+		handlers.MethodNotAllowed(w, r)
+	}
+}
+
+func (router *routerImpl) handleRotationPauseFunc(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimRight(r.URL.Path, "/") != "/rotation/pause" {
+		handlers.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		router.handlers.PauseBanner(w, r) // Приостановка показов баннера
+	default:
+		handlers.MethodNotAllowed(w, r)
+	}
+}
+
+func (router *routerImpl) handleRotationResumeFunc(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimRight(r.URL.Path, "/") != "/rotation/resume" {
+		handlers.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		router.handlers.ResumeBanner(w, r) // Возобновление показов баннера
+	default:
+		handlers.MethodNotAllowed(w, r)
+	}
+}
+
+func (router *routerImpl) handleStatisticsFunc(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimRight(r.URL.Path, "/") != "/statistics" {
+		handlers.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		router.handlers.GetStatistics(w, r) // Статистика показов и переходов
+	default:
+		handlers.MethodNotAllowed(w, r)
+	}
+}
+
+func (router *routerImpl) handleStatisticsHistoryFunc(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimRight(r.URL.Path, "/") != "/statistics/history" {
+		handlers.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		router.handlers.GetStatisticsHistory(w, r) // История статистики по часам или суткам
+	default:
+		handlers.MethodNotAllowed(w, r)
 	}
 }
@@ -5,7 +5,9 @@ import (
 	"strings"
 
 	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/frequencycap"
 	"github.com/SergeyTyurin/banner-rotation/handlers"
+	"github.com/SergeyTyurin/banner-rotation/impression"
 	"github.com/SergeyTyurin/banner-rotation/messagebroker"
 )
 
@@ -18,22 +20,29 @@ type routerImpl struct {
 	mux      *http.ServeMux
 }
 
-func NewRouter(db database.Database, broker messagebroker.MessageBroker) Router {
+func NewRouter(db database.Database, broker messagebroker.MessageBroker,
+	capper frequencycap.Capper, impressions impression.Signer,
+) Router {
 	var r routerImpl
 	r.mux = http.NewServeMux()
 	r.mux.HandleFunc("/banner", r.handleBannersFunc)
 	r.mux.HandleFunc("/slot", r.handleSlotsFunc)
 	r.mux.HandleFunc("/group", r.handleGroupsFunc)
 	r.mux.HandleFunc("/rotation", r.handleRotationFunc)
+	r.mux.HandleFunc("/rotation/members", r.handleRotationMembersFunc)
+	r.mux.HandleFunc("/rotation/pause", r.handleRotationPauseFunc)
+	r.mux.HandleFunc("/rotation/resume", r.handleRotationResumeFunc)
+	r.mux.HandleFunc("/statistics", r.handleStatisticsFunc)
+	r.mux.HandleFunc("/statistics/history", r.handleStatisticsHistoryFunc)
 	r.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if strings.TrimRight(r.URL.Path, "/") != "" {
-			http.NotFound(w, r)
+			handlers.NotFound(w, r)
 			return
 		}
 		_, _ = w.Write([]byte("Rotation service is running"))
 	})
 
-	r.handlers = handlers.NewHandlers(db, broker)
+	r.handlers = handlers.NewHandlers(db, broker, capper, impressions)
 	return &r
 }
 
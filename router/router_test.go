@@ -2,11 +2,14 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/SergeyTyurin/banner-rotation/database"
+	"github.com/SergeyTyurin/banner-rotation/structures"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,7 +19,8 @@ var (
 )
 
 func TestCorrectURL(t *testing.T) {
-	mux := NewRouter(nil, nil).CustomMux()
+	// Запрос списка без идентификатора обращается к базе
+	mux := NewRouter(database.NewMemoryDatabase(), nil, nil, nil).CustomMux()
 	urls := []struct {
 		url    string
 		method string
@@ -37,6 +41,11 @@ func TestCorrectURL(t *testing.T) {
 		{"rotation", http.MethodPut},
 		{"rotation", http.MethodDelete},
 		{"rotation", http.MethodPost},
+		{"rotation/members", http.MethodGet},
+		{"rotation/pause", http.MethodPost},
+		{"rotation/resume", http.MethodPost},
+		{"statistics", http.MethodGet},
+		{"statistics/history", http.MethodGet},
 		{"", http.MethodPost},
 	}
 
@@ -50,7 +59,7 @@ func TestCorrectURL(t *testing.T) {
 }
 
 func TestIncorrectURL(t *testing.T) {
-	mux := NewRouter(nil, nil).CustomMux()
+	mux := NewRouter(nil, nil, nil, nil).CustomMux()
 	urls := []struct {
 		url    string
 		method string
@@ -59,9 +68,12 @@ func TestIncorrectURL(t *testing.T) {
 		{"banner/rotation", http.MethodPut},
 		{"slots/", http.MethodGet},
 		{"statistic", http.MethodPut},
+		{"statistics/banner", http.MethodGet},
 		{"groups", http.MethodGet},
 		{"groups/", http.MethodPut},
 		{"rotation/get", http.MethodGet},
+		{"rotation/members/1", http.MethodGet},
+		{"rotation/pause/1", http.MethodPost},
 	}
 
 	for _, test := range urls {
@@ -74,7 +86,7 @@ func TestIncorrectURL(t *testing.T) {
 }
 
 func TestIncorrectMethod(t *testing.T) {
-	mux := NewRouter(nil, nil).CustomMux()
+	mux := NewRouter(database.NewMemoryDatabase(), nil, nil, nil).CustomMux()
 	urls := []struct {
 		url    string
 		method string
@@ -93,3 +105,66 @@ func TestIncorrectMethod(t *testing.T) {
 		require.NotEqual(t, http.StatusMethodNotAllowed, response.Code, url)
 	}
 }
+
+func TestRouterErrorEnvelope(t *testing.T) {
+	mux := NewRouter(database.NewMemoryDatabase(), nil, nil, nil).CustomMux()
+	tests := []struct {
+		url     string
+		method  string
+		status  int
+		code    string
+		message string
+	}{
+		{"banners", http.MethodGet, http.StatusNotFound, "not_found", "page not found"},
+		{"banner", http.MethodPatch, http.StatusMethodNotAllowed, "method_not_allowed", "method not allowed"},
+	}
+
+	for _, test := range tests {
+		url := fmt.Sprintf("http://%s:%d/%s", testHost, testPort, test.url)
+		request, _ := http.NewRequestWithContext(context.Background(), test.method, url, nil)
+		request.Header.Set("Accept", "application/json")
+		response := httptest.NewRecorder()
+		mux.ServeHTTP(response, request)
+		require.Equal(t, test.status, response.Code, url)
+		require.JSONEq(t, fmt.Sprintf(`{"error":{"code":%q,"message":%q}}`, test.code, test.message),
+			response.Body.String(), url)
+
+		// Текстовый клиент по-прежнему получает текст.
+		request.Header.Del("Accept")
+		response = httptest.NewRecorder()
+		mux.ServeHTTP(response, request)
+		require.Equal(t, test.status, response.Code, url)
+		require.Equal(t, test.message, response.Body.String(), url)
+	}
+}
+
+func TestStatisticsRoute(t *testing.T) {
+	d := database.NewMemoryDatabase()
+	banner, _ := d.DatabaseCreateBanner(structures.Banner{Info: "banner"})
+	slot, _ := d.DatabaseCreateSlot(structures.Slot{Info: "slot"})
+	group, _ := d.DatabaseCreateGroup(structures.Group{Info: "group"})
+	_ = d.DatabaseAddToRotation(banner.ID, slot.ID)
+	_, _ = database.SelectFromRotation(d, slot.ID, group.ID)
+	mux := NewRouter(d, nil, nil, nil).CustomMux()
+
+	url := fmt.Sprintf("http://%s:%d/statistics?slot_id=%d&group_id=%d", testHost, testPort, slot.ID, group.ID)
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	response := httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	require.Equal(t, http.StatusOK, response.Code)
+	var statistics []structures.Statistic
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &statistics))
+	require.Len(t, statistics, 1)
+	require.Equal(t, banner.ID, statistics[0].BannerID)
+	require.Equal(t, 1, statistics[0].Displays)
+
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodGet, url+"&banner_id=x", nil)
+	response = httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	require.Equal(t, http.StatusBadRequest, response.Code)
+
+	request, _ = http.NewRequestWithContext(context.Background(), http.MethodPost, url, nil)
+	response = httptest.NewRecorder()
+	mux.ServeHTTP(response, request)
+	require.Equal(t, http.StatusMethodNotAllowed, response.Code)
+}
@@ -1,16 +1,162 @@
 package structures
 
+import "time"
+
+// Статусы баннера.
+const (
+	BannerActive   = "active"
+	BannerPaused   = "paused"
+	BannerArchived = "archived"
+)
+
+// Banner - рекламный баннер. TargetURL - адрес перехода по баннеру,
+// CreativeURL - адрес изображения, Width и Height - его размер в пикселях.
+// Время создания и изменения устанавливается базой.
 type Banner struct {
-	ID   int    `json:"id"`
-	Info string `json:"info"`
+	ID          int       `json:"id"`
+	Info        string    `json:"info"`
+	TargetURL   string    `json:"target_url,omitempty"`
+	CreativeURL string    `json:"creative_url,omitempty"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	Status      string    `json:"status,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// Group - социально-демографическая группа пользователей.
 type Group struct {
-	ID   int    `json:"id"`
-	Info string `json:"info"`
+	ID          int    `json:"id"`
+	Info        string `json:"info"`
+	Description string `json:"description,omitempty"`
+}
+
+// Strategy - алгоритм выбора баннера в слоте и его параметры.
+// Незаданный параметр принимает значение по умолчанию алгоритма.
+type Strategy struct {
+	Algorithm   string   `json:"algorithm"`
+	Epsilon     *float64 `json:"epsilon,omitempty"`
+	Exploration *float64 `json:"exploration,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Alpha       *float64 `json:"alpha,omitempty"`
+	Beta        *float64 `json:"beta,omitempty"`
+	Discount    *float64 `json:"discount,omitempty"`
+	Window      *int     `json:"window,omitempty"`
 }
 
+// Slot - место на странице для показа баннеров. Placement - расположение слота на странице,
+// Width и Height - размер в пикселях.
 type Slot struct {
-	ID   int    `json:"id"`
-	Info string `json:"info"`
+	ID        int      `json:"id"`
+	Info      string   `json:"info"`
+	Width     int      `json:"width,omitempty"`
+	Height    int      `json:"height,omitempty"`
+	Placement string   `json:"placement,omitempty"`
+	Strategy  Strategy `json:"strategy"`
+}
+
+// Поля сортировки списков сущностей.
+const (
+	SortByID   = "id"
+	SortByInfo = "info"
+)
+
+// ListParams - параметры получения списка сущностей.
+// Нулевой Limit означает отсутствие ограничения, Info - подстрока описания без учета регистра.
+type ListParams struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+	Info   string
+}
+
+// RotationFilter - условия выборки состава ротации. Нулевой идентификатор означает отсутствие условия.
+type RotationFilter struct {
+	SlotID   int
+	BannerID int
+}
+
+// RotationMember - баннер в ротации слота и его счетчики по группам пользователей.
+type RotationMember struct {
+	SlotID     int         `json:"slot_id"`
+	Banner     Banner      `json:"banner"`
+	Status     string      `json:"status"`
+	AddedAt    time.Time   `json:"added_at"`
+	Statistics []Statistic `json:"statistics"`
+	Flight
+	Priority
+}
+
+// Flight - период показов баннера в ротации слота. Отсутствующая граница период не ограничивает.
+type Flight struct {
+	StartAt *time.Time `json:"start_at,omitempty"`
+	EndAt   *time.Time `json:"end_at,omitempty"`
+}
+
+// Priority - приоритет баннера в ротации слота: множитель оценки алгоритма
+// и гарантированная доля показов слота (от 0 до 1).
+type Priority struct {
+	Weight float64 `json:"weight"`
+	Share  float64 `json:"share"`
+}
+
+// Selection - баннер, выбранный для показа в слоте. ImpressionID идентифицирует показ,
+// Algorithm - алгоритм слота, которым выбран баннер.
+type Selection struct {
+	Banner       Banner `json:"banner"`
+	SlotID       int    `json:"slot_id"`
+	GroupID      int    `json:"group_id"`
+	ImpressionID string `json:"impression_id"`
+	Algorithm    string `json:"algorithm"`
+}
+
+// SelectedBanners - баннеры в порядке выбора и алгоритм слота, которым они выбраны.
+type SelectedBanners struct {
+	Banners   []Banner
+	Algorithm string
+}
+
+// StatisticFilter - условия выборки статистики. Нулевой идентификатор означает отсутствие условия.
+type StatisticFilter struct {
+	SlotID   int
+	GroupID  int
+	BannerID int
+}
+
+// Statistic - счетчики баннера в ротации слота для группы пользователей.
+// Score - текущая оценка UCB1, для баннера без показов не определена.
+type Statistic struct {
+	SlotID   int      `json:"slot_id"`
+	GroupID  int      `json:"group_id"`
+	BannerID int      `json:"banner_id"`
+	Displays int      `json:"displays"`
+	Clicks   int      `json:"clicks"`
+	CTR      float64  `json:"ctr"`
+	Score    *float64 `json:"score"`
+}
+
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// HistoryFilter - условия выборки истории статистики за период [From, To).
+// Нулевое время означает отсутствие границы, пустая детализация - почасовую.
+type HistoryFilter struct {
+	StatisticFilter
+	From        time.Time
+	To          time.Time
+	Granularity string
+}
+
+// HistoryPoint - показы и переходы баннера за час или сутки, начинающиеся в Time.
+type HistoryPoint struct {
+	SlotID   int       `json:"slot_id"`
+	GroupID  int       `json:"group_id"`
+	BannerID int       `json:"banner_id"`
+	Time     time.Time `json:"time"`
+	Displays int       `json:"displays"`
+	Clicks   int       `json:"clicks"`
+	CTR      float64   `json:"ctr"`
 }